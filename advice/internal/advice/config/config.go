@@ -11,6 +11,8 @@ type Config struct {
 	PostgresPassword string
 	PostgresDatabase string
 	PostgresSSLMode  string
+
+	WeightByIntensity bool
 }
 
 func GetConfig() *Config {
@@ -23,5 +25,7 @@ func GetConfig() *Config {
 		PostgresPassword: configutil.GetEnv("POSTGRES_PASSWORD", "password"),
 		PostgresDatabase: configutil.GetEnv("POSTGRES_DATABASE", "mood_api_db"),
 		PostgresSSLMode:  configutil.GetEnv("POSTGRES_SSLMODE", "disable"),
+
+		WeightByIntensity: configutil.GetEnvBool("ADVICE_WEIGHT_BY_INTENSITY", true),
 	}
 }
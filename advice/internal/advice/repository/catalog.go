@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ErrInvalidReference is returned when a write points at a missing row or a delete would orphan rows pointing at it
+var ErrInvalidReference = errors.New("invalid reference")
+
+type AdviceType struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type Advice struct {
+	ID           int       `json:"id"`
+	AdviceTypeID int       `json:"adviceTypeId"`
+	Title        string    `json:"title"`
+	Content      string    `json:"content"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+func (o *DBOperations) GetAdviceTypes() ([]AdviceType, error) {
+	adviceTypes := make([]AdviceType, 0)
+	query := `
+		SELECT id, name, COALESCE(description, '')
+		FROM public.advice_type
+		ORDER BY id;
+	`
+
+	rows, err := o.Postgres.DB.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var at AdviceType
+		if err := rows.Scan(&at.ID, &at.Name, &at.Description); err != nil {
+			return nil, err
+		}
+		adviceTypes = append(adviceTypes, at)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return adviceTypes, nil
+}
+
+func (o *DBOperations) GetAllAdvice() ([]Advice, error) {
+	advice := make([]Advice, 0)
+	query := `
+		SELECT id, advice_type_id, COALESCE(title, ''), content, created_at
+		FROM public.advice
+		ORDER BY id;
+	`
+
+	rows, err := o.Postgres.DB.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var a Advice
+		if err := rows.Scan(&a.ID, &a.AdviceTypeID, &a.Title, &a.Content, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		advice = append(advice, a)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return advice, nil
+}
+
+func (o *DBOperations) AddAdvice(adviceTypeID int, title, content string) (*Advice, error) {
+	a := Advice{AdviceTypeID: adviceTypeID, Title: title, Content: content}
+	query := `
+		INSERT INTO public.advice (advice_type_id, title, content)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at;
+	`
+
+	err := o.Postgres.DB.QueryRow(query, adviceTypeID, title, content).Scan(&a.ID, &a.CreatedAt)
+	if err != nil {
+		return nil, mapForeignKeyError(err)
+	}
+
+	return &a, nil
+}
+
+// UpdateAdvice returns sql.ErrNoRows when the advice does not exist
+func (o *DBOperations) UpdateAdvice(adviceID, adviceTypeID int, title, content string) error {
+	var id int
+	query := `
+		UPDATE public.advice
+		SET advice_type_id = $1, title = $2, content = $3
+		WHERE id = $4
+		RETURNING id;
+	`
+
+	err := o.Postgres.DB.QueryRow(query, adviceTypeID, title, content, adviceID).Scan(&id)
+	if err != nil {
+		return mapForeignKeyError(err)
+	}
+
+	return nil
+}
+
+// DeleteAdvice returns sql.ErrNoRows when the advice does not exist
+func (o *DBOperations) DeleteAdvice(adviceID int) error {
+	var id int
+	query := `
+		DELETE FROM public.advice
+		WHERE id = $1
+		RETURNING id;
+	`
+
+	err := o.Postgres.DB.QueryRow(query, adviceID).Scan(&id)
+	if err != nil {
+		return mapForeignKeyError(err)
+	}
+
+	return nil
+}
+
+func mapForeignKeyError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+		return ErrInvalidReference
+	}
+	return err
+}
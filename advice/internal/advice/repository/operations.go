@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"time"
+
 	"github.com/ciameksw/mood-api/pkg/postgres"
 	"github.com/lib/pq"
 )
@@ -161,3 +163,56 @@ func (o *DBOperations) SaveUserAdvicePeriod(userID int, adviceID int, periodFrom
 	}
 	return id, nil
 }
+
+// DeleteUserAdvicePeriods deletes the advice history of a user and returns how many periods there were
+func (o *DBOperations) DeleteUserAdvicePeriods(userID int) (int64, error) {
+	query := "DELETE FROM public.user_advice_periods WHERE user_id = $1"
+
+	result, err := o.Postgres.DB.Exec(query, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+type AdviceHistoryEntry struct {
+	ID         int       `json:"id"`
+	PeriodFrom string    `json:"periodFrom"`
+	PeriodTo   string    `json:"periodTo"`
+	AdviceID   int       `json:"adviceId"`
+	AdviceType string    `json:"adviceType"`
+	Title      string    `json:"title"`
+	Content    string    `json:"content"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// ForEachUserAdvicePeriod calls fn for every advice given to a user, oldest period first, without loading them all into memory
+func (o *DBOperations) ForEachUserAdvicePeriod(userID int, fn func(AdviceHistoryEntry) error) error {
+	query := `
+		SELECT ap.id, to_char(ap.period_from, 'YYYY-MM-DD'), to_char(ap.period_to, 'YYYY-MM-DD'), a.id, COALESCE(at.name, ''), COALESCE(a.title, ''), a.content, ap.created_at
+		FROM public.user_advice_periods ap
+		JOIN public.advice a ON a.id = ap.advice_id
+		LEFT JOIN public.advice_type at ON at.id = a.advice_type_id
+		WHERE ap.user_id = $1
+		ORDER BY ap.period_from, ap.id;
+	`
+
+	rows, err := o.Postgres.DB.Query(query, userID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e AdviceHistoryEntry
+		if err := rows.Scan(&e.ID, &e.PeriodFrom, &e.PeriodTo, &e.AdviceID, &e.AdviceType, &e.Title, &e.Content, &e.CreatedAt); err != nil {
+			return err
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
@@ -16,6 +16,8 @@ type selectAdviceInputEntry struct {
 	MoodTypeID int     `json:"moodTypeId" validate:"required"`
 	Count      int     `json:"count" validate:"required,min=1"`
 	Percentage float64 `json:"percentage" validate:"required"`
+	// Share of the mood weighted by intensity, optional for callers that do not record intensity
+	WeightedPercentage float64 `json:"weightedPercentage" validate:"min=0,max=100"`
 }
 
 func (s *Server) handleSelectAdvice(w http.ResponseWriter, r *http.Request) {
@@ -30,11 +32,11 @@ func (s *Server) handleSelectAdvice(w http.ResponseWriter, r *http.Request) {
 
 	err = s.Validator.Var(input, "required,dive")
 	if err != nil {
-		httputil.HandleError(*s.Logger, w, err.Error(), err, http.StatusBadRequest)
+		httputil.HandleValidationError(*s.Logger, w, err)
 		return
 	}
 
-	moodSummary := convertToMoodSummary(input)
+	moodSummary := convertToMoodSummary(input, s.Config.WeightByIntensity)
 
 	adviceTypeID, err := s.DBOperations.GetAdviceTypeIDByMoodSummary(moodSummary)
 	if err != nil {
@@ -60,13 +62,17 @@ func (s *Server) handleSelectAdvice(w http.ResponseWriter, r *http.Request) {
 	httputil.WriteData(*s.Logger, w, response, http.StatusOK)
 }
 
-// Helper function to convert input to MoodSummaryEntry slice
-func convertToMoodSummary(input []selectAdviceInputEntry) []repository.MoodSummaryEntry {
+// Helper function to convert input to MoodSummaryEntry slice, preferring intensity-weighted percentages when weighted
+func convertToMoodSummary(input []selectAdviceInputEntry, weighted bool) []repository.MoodSummaryEntry {
 	summary := make([]repository.MoodSummaryEntry, len(input))
 	for i, entry := range input {
+		percentage := entry.Percentage
+		if weighted && entry.WeightedPercentage > 0 {
+			percentage = entry.WeightedPercentage
+		}
 		summary[i] = repository.MoodSummaryEntry{
 			MoodTypeID: entry.MoodTypeID,
-			Percentage: entry.Percentage,
+			Percentage: percentage,
 		}
 	}
 	return summary
@@ -89,7 +95,7 @@ func (s *Server) handleSaveAdvice(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := s.Validator.Struct(req); err != nil {
-		httputil.HandleError(*s.Logger, w, err.Error(), err, http.StatusBadRequest)
+		httputil.HandleValidationError(*s.Logger, w, err)
 		return
 	}
 
@@ -109,6 +115,59 @@ func (s *Server) handleSaveAdvice(w http.ResponseWriter, r *http.Request) {
 	httputil.WriteData(*s.Logger, w, response, http.StatusCreated)
 }
 
+// handleDeleteUserAdvice erases a user's advice history when their account is purged, it is not exposed by the gateway
+func (s *Server) handleDeleteUserAdvice(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Deleting advice history of user")
+
+	userID, err := strconv.Atoi(r.PathValue("userId"))
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Invalid userId parameter", err, http.StatusBadRequest)
+		return
+	}
+
+	deleted, err := s.DBOperations.DeleteUserAdvicePeriods(userID)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to delete advice history", err, http.StatusInternalServerError)
+		return
+	}
+
+	s.Logger.Info.Printf("Deleted %d advice periods of user %d", deleted, userID)
+	httputil.WriteSuccessMessage(*s.Logger, w, "Advice history of user deleted", http.StatusOK)
+}
+
+// handleExportUserAdvice streams the advice history of a user as NDJSON for the data export, it is not exposed by the gateway
+func (s *Server) handleExportUserAdvice(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Exporting advice history of user")
+
+	userID, err := strconv.Atoi(r.PathValue("userId"))
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Invalid userId parameter", err, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	enc := json.NewEncoder(w)
+	exported := 0
+	err = s.DBOperations.ForEachUserAdvicePeriod(userID, func(e repository.AdviceHistoryEntry) error {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+		exported++
+		return nil
+	})
+	if err != nil {
+		if exported == 0 {
+			httputil.HandleError(*s.Logger, w, "Failed to export advice history", err, http.StatusInternalServerError)
+			return
+		}
+		// The status is already sent, aborting the connection keeps the caller from taking a partial export as complete
+		s.Logger.Error.Printf("Failed to export advice history of user %d: %v", userID, err)
+		panic(http.ErrAbortHandler)
+	}
+
+	s.Logger.Info.Printf("Exported %d advice periods of user %d", exported, userID)
+}
+
 func (s *Server) handleGetByID(w http.ResponseWriter, r *http.Request) {
 	s.Logger.Info.Println("Getting advice by ID")
 
@@ -0,0 +1,133 @@
+package server
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/ciameksw/mood-api/advice/internal/advice/repository"
+	"github.com/ciameksw/mood-api/pkg/httputil"
+)
+
+func (s *Server) handleGetAdviceTypes(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Getting advice types")
+
+	adviceTypes, err := s.DBOperations.GetAdviceTypes()
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to get advice types", err, http.StatusInternalServerError)
+		return
+	}
+
+	httputil.WriteData(*s.Logger, w, adviceTypes, http.StatusOK)
+}
+
+func (s *Server) handleGetCatalog(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Getting advice catalog")
+
+	advice, err := s.DBOperations.GetAllAdvice()
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to get advice catalog", err, http.StatusInternalServerError)
+		return
+	}
+
+	httputil.WriteData(*s.Logger, w, advice, http.StatusOK)
+}
+
+type catalogAdviceRequest struct {
+	AdviceTypeID int    `json:"adviceTypeId" validate:"required"`
+	Title        string `json:"title" validate:"required,max=200"`
+	Content      string `json:"content" validate:"required"`
+}
+
+func (s *Server) handleAddCatalogAdvice(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Adding advice to catalog")
+
+	var req catalogAdviceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httputil.HandleError(*s.Logger, w, "Invalid request payload", err, http.StatusBadRequest)
+		return
+	}
+
+	if err := s.Validator.Struct(req); err != nil {
+		httputil.HandleValidationError(*s.Logger, w, err)
+		return
+	}
+
+	advice, err := s.DBOperations.AddAdvice(req.AdviceTypeID, req.Title, req.Content)
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidReference) {
+			httputil.HandleError(*s.Logger, w, "Advice type not found", err, http.StatusBadRequest)
+			return
+		}
+		httputil.HandleError(*s.Logger, w, "Failed to add advice", err, http.StatusInternalServerError)
+		return
+	}
+
+	httputil.WriteData(*s.Logger, w, advice, http.StatusCreated)
+}
+
+func (s *Server) handleUpdateCatalogAdvice(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Updating advice in catalog")
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Invalid id parameter", err, http.StatusBadRequest)
+		return
+	}
+
+	var req catalogAdviceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httputil.HandleError(*s.Logger, w, "Invalid request payload", err, http.StatusBadRequest)
+		return
+	}
+
+	if err := s.Validator.Struct(req); err != nil {
+		httputil.HandleValidationError(*s.Logger, w, err)
+		return
+	}
+
+	err = s.DBOperations.UpdateAdvice(id, req.AdviceTypeID, req.Title, req.Content)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			httputil.HandleError(*s.Logger, w, "Advice not found", err, http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, repository.ErrInvalidReference) {
+			httputil.HandleError(*s.Logger, w, "Advice type not found", err, http.StatusBadRequest)
+			return
+		}
+		httputil.HandleError(*s.Logger, w, "Failed to update advice", err, http.StatusInternalServerError)
+		return
+	}
+
+	httputil.WriteSuccessMessage(*s.Logger, w, "Advice updated", http.StatusOK)
+}
+
+func (s *Server) handleDeleteCatalogAdvice(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Deleting advice from catalog")
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Invalid id parameter", err, http.StatusBadRequest)
+		return
+	}
+
+	err = s.DBOperations.DeleteAdvice(id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			httputil.HandleError(*s.Logger, w, "Advice not found", err, http.StatusNotFound)
+			return
+		}
+		// Advice already handed out to users stays referenced by their saved periods
+		if errors.Is(err, repository.ErrInvalidReference) {
+			httputil.HandleError(*s.Logger, w, "Advice has been given to users and cannot be deleted", err, http.StatusConflict)
+			return
+		}
+		httputil.HandleError(*s.Logger, w, "Failed to delete advice", err, http.StatusInternalServerError)
+		return
+	}
+
+	httputil.WriteSuccessMessage(*s.Logger, w, "Advice deleted", http.StatusOK)
+}
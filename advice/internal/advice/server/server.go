@@ -6,6 +6,7 @@ import (
 
 	"github.com/ciameksw/mood-api/advice/internal/advice/config"
 	"github.com/ciameksw/mood-api/advice/internal/advice/repository"
+	"github.com/ciameksw/mood-api/pkg/httputil"
 	"github.com/ciameksw/mood-api/pkg/logger"
 	"github.com/ciameksw/mood-api/pkg/postgres"
 	"github.com/go-playground/validator/v10"
@@ -24,7 +25,7 @@ func NewServer(log *logger.Logger, cfg *config.Config, pg *postgres.PostgresDB)
 		Logger:       log,
 		Config:       cfg,
 		DBOperations: &repository.DBOperations{Postgres: pg},
-		Validator:    validator.New(),
+		Validator:    httputil.NewValidator(),
 	}
 }
 
@@ -34,7 +35,14 @@ func (s *Server) Start() error {
 	r.HandleFunc("POST /advice/select", s.handleSelectAdvice)
 	r.HandleFunc("POST /advice/period/save", s.handleSaveAdvice)
 	r.HandleFunc("GET /advice/period/get", s.handleGetAdviceByPeriod)
+	r.HandleFunc("GET /advice/types", s.handleGetAdviceTypes)
+	r.HandleFunc("GET /advice/catalog", s.handleGetCatalog)
+	r.HandleFunc("POST /advice/catalog", s.handleAddCatalogAdvice)
+	r.HandleFunc("PUT /advice/catalog/{id}", s.handleUpdateCatalogAdvice)
+	r.HandleFunc("DELETE /advice/catalog/{id}", s.handleDeleteCatalogAdvice)
 	r.HandleFunc("GET /advice/{id}", s.handleGetByID)
+	r.HandleFunc("DELETE /advice/user/{userId}", s.handleDeleteUserAdvice)
+	r.HandleFunc("GET /advice/user/{userId}/export", s.handleExportUserAdvice)
 
 	r.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
@@ -8,7 +8,9 @@ import (
 	"time"
 
 	"github.com/ciameksw/mood-api/auth/internal/auth/config"
+	"github.com/ciameksw/mood-api/auth/internal/auth/passwordpolicy"
 	"github.com/ciameksw/mood-api/auth/internal/auth/server"
+	"github.com/ciameksw/mood-api/auth/internal/auth/token"
 	"github.com/ciameksw/mood-api/pkg/logger"
 	"github.com/ciameksw/mood-api/pkg/postgres"
 )
@@ -26,7 +28,61 @@ func main() {
 		lgr.Error.Fatalf("Failed to connect to Postgres: %v", err)
 	}
 
-	s := server.NewServer(lgr, cfg, db)
+	// Load JWT signing keys
+	var keys *token.KeySet
+	if cfg.JWTKeysDir == "" {
+		lgr.Error.Println("JWT_KEYS_DIR is not set, generating an ephemeral signing key (tokens will not survive a restart)")
+		keys, err = token.GenerateEphemeralKeySet()
+	} else {
+		keys, err = token.LoadKeySet(cfg.JWTKeysDir, cfg.JWTSigningKeyID)
+	}
+	if err != nil {
+		lgr.Error.Fatalf("Failed to load JWT keys: %v", err)
+	}
+	lgr.Info.Printf("Signing tokens with key %s", keys.SigningKID())
+
+	// Load the key encrypting two-factor secrets
+	var secrets *token.SecretCipher
+	if cfg.TOTPEncryptionKey == "" {
+		lgr.Error.Println("TOTP_ENCRYPTION_KEY is not set, two-factor authentication is disabled")
+	} else {
+		secrets, err = token.NewSecretCipher(cfg.TOTPEncryptionKey)
+		if err != nil {
+			lgr.Error.Fatalf("Failed to load TOTP encryption key: %v", err)
+		}
+	}
+
+	// Set up password hashing, stored hashes made with other settings are upgraded on login
+	passwords, err := token.NewPasswordHasher(token.PasswordParams{
+		Algorithm:     cfg.PasswordHashAlgorithm,
+		BcryptCost:    cfg.BcryptCost,
+		Argon2Memory:  uint32(cfg.Argon2Memory),
+		Argon2Time:    uint32(cfg.Argon2Time),
+		Argon2Threads: uint8(cfg.Argon2Threads),
+	})
+	if err != nil {
+		lgr.Error.Fatalf("Invalid password hashing settings: %v", err)
+	}
+
+	// Load the rules new passwords have to satisfy
+	policy, err := passwordpolicy.NewPolicy(cfg)
+	if err != nil {
+		lgr.Error.Fatalf("Invalid password policy: %v", err)
+	}
+	if cfg.BreachedPasswordsFile == "" {
+		lgr.Error.Println("BREACHED_PASSWORDS_FILE is not set, new passwords are not checked against breached passwords")
+	} else {
+		lgr.Info.Printf("Loaded %d breached passwords", policy.BreachedCount())
+	}
+
+	s := server.NewServer(lgr, cfg, db, keys, secrets, passwords, policy)
+
+	// Grant the admin role to the configured first admin if the account already exists
+	s.BootstrapAdmin(context.Background())
+
+	// Start background jobs, stopped on shutdown
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	s.StartJobs(jobsCtx)
 
 	// Start server in a goroutine
 	go func() {
@@ -42,6 +98,7 @@ func main() {
 	<-quit
 
 	lgr.Info.Println("Shutting down server...")
+	stopJobs()
 
 	// Create shutdown context with timeout
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -1,6 +1,11 @@
 package config
 
-import "github.com/ciameksw/mood-api/pkg/configutil"
+import (
+	"strings"
+	"time"
+
+	"github.com/ciameksw/mood-api/pkg/configutil"
+)
 
 type Config struct {
 	ServerHost       string
@@ -11,7 +16,78 @@ type Config struct {
 	PostgresPassword string
 	PostgresDatabase string
 	PostgresSSLMode  string
-	Salt             string
+	JWTKeysDir       string
+	JWTSigningKeyID  string
+	RefreshTokenTTL  time.Duration
+	PasswordResetTTL time.Duration
+	AppBaseURL       string
+	Mailer           string
+	MailFrom         string
+	MailFilePath     string
+	SMTPHost         string
+	SMTPPort         string
+	SMTPUsername     string
+	SMTPPassword     string
+
+	RequireEmailVerification        bool
+	EmailVerificationTTL            time.Duration
+	EmailVerificationResendInterval time.Duration
+
+	TOTPEncryptionKey     string
+	TOTPIssuer            string
+	TwoFactorChallengeTTL time.Duration
+
+	LockoutStore            string
+	RedisAddr               string
+	RedisPassword           string
+	LoginMaxAccountFailures int
+	LoginMaxIPFailures      int
+	LoginLockoutBase        time.Duration
+	LoginLockoutMax         time.Duration
+	LoginFailureWindow      time.Duration
+
+	AdminBootstrapEmail string
+
+	PasswordHashAlgorithm string
+	BcryptCost            int
+	Argon2Memory          int
+	Argon2Time            int
+	Argon2Threads         int
+
+	PasswordMinLength            int
+	PasswordMaxLength            int
+	PasswordRequireUpper         bool
+	PasswordRequireLower         bool
+	PasswordRequireDigit         bool
+	PasswordRequireSymbol        bool
+	PasswordDisallowPersonalInfo bool
+	BreachedPasswordsFile        string
+
+	AccountDeletionGracePeriod time.Duration
+	MoodURL                    string
+	AdviceURL                  string
+
+	AuthEventRetention time.Duration
+
+	MagicLinkTTL         time.Duration
+	MagicLinkBindDevice  bool
+	MagicLinkMaxPerEmail int
+	MagicLinkRateWindow  time.Duration
+
+	OIDCProviders       []OIDCProvider
+	OIDCRedirectBaseURL string
+	OIDCLoginTTL        time.Duration
+}
+
+// OIDCProvider configures one OpenID Connect identity provider users can sign in with
+type OIDCProvider struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	// AuthorizationURL overrides the discovered authorization endpoint when browsers reach the provider under another host
+	AuthorizationURL string
 }
 
 func GetConfig() *Config {
@@ -24,6 +100,88 @@ func GetConfig() *Config {
 		PostgresPassword: configutil.GetEnv("POSTGRES_PASSWORD", "password"),
 		PostgresDatabase: configutil.GetEnv("POSTGRES_DATABASE", "mood_api_db"),
 		PostgresSSLMode:  configutil.GetEnv("POSTGRES_SSLMODE", "disable"),
-		Salt:             configutil.GetEnv("SALT", "Nb58PsZJlCiO"),
+		JWTKeysDir:       configutil.GetEnv("JWT_KEYS_DIR", ""),
+		JWTSigningKeyID:  configutil.GetEnv("JWT_SIGNING_KEY_ID", ""),
+		RefreshTokenTTL:  configutil.GetEnvDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour),
+		PasswordResetTTL: configutil.GetEnvDuration("PASSWORD_RESET_TTL", time.Hour),
+		AppBaseURL:       configutil.GetEnv("APP_BASE_URL", "http://localhost:3000"),
+		Mailer:           configutil.GetEnv("MAILER", "stdout"),
+		MailFrom:         configutil.GetEnv("MAIL_FROM", "no-reply@mood-api.local"),
+		MailFilePath:     configutil.GetEnv("MAIL_FILE_PATH", "mail.log"),
+		SMTPHost:         configutil.GetEnv("SMTP_HOST", "localhost"),
+		SMTPPort:         configutil.GetEnv("SMTP_PORT", "587"),
+		SMTPUsername:     configutil.GetEnv("SMTP_USERNAME", ""),
+		SMTPPassword:     configutil.GetEnv("SMTP_PASSWORD", ""),
+
+		RequireEmailVerification:        configutil.GetEnvBool("REQUIRE_EMAIL_VERIFICATION", false),
+		EmailVerificationTTL:            configutil.GetEnvDuration("EMAIL_VERIFICATION_TTL", 24*time.Hour),
+		EmailVerificationResendInterval: configutil.GetEnvDuration("EMAIL_VERIFICATION_RESEND_INTERVAL", time.Minute),
+
+		TOTPEncryptionKey:     configutil.GetEnv("TOTP_ENCRYPTION_KEY", ""),
+		TOTPIssuer:            configutil.GetEnv("TOTP_ISSUER", "Mood API"),
+		TwoFactorChallengeTTL: configutil.GetEnvDuration("TWO_FACTOR_CHALLENGE_TTL", 5*time.Minute),
+
+		LockoutStore:            configutil.GetEnv("LOCKOUT_STORE", "memory"),
+		RedisAddr:               configutil.GetEnv("REDIS_ADDR", "localhost:6379"),
+		RedisPassword:           configutil.GetEnv("REDIS_PASSWORD", ""),
+		LoginMaxAccountFailures: configutil.GetEnvInt("LOGIN_MAX_ACCOUNT_FAILURES", 5),
+		LoginMaxIPFailures:      configutil.GetEnvInt("LOGIN_MAX_IP_FAILURES", 20),
+		LoginLockoutBase:        configutil.GetEnvDuration("LOGIN_LOCKOUT_BASE", time.Minute),
+		LoginLockoutMax:         configutil.GetEnvDuration("LOGIN_LOCKOUT_MAX", time.Hour),
+		LoginFailureWindow:      configutil.GetEnvDuration("LOGIN_FAILURE_WINDOW", 24*time.Hour),
+
+		AdminBootstrapEmail: configutil.GetEnv("ADMIN_BOOTSTRAP_EMAIL", ""),
+
+		PasswordHashAlgorithm: configutil.GetEnv("PASSWORD_HASH_ALGORITHM", "argon2id"),
+		BcryptCost:            configutil.GetEnvInt("BCRYPT_COST", 12),
+		Argon2Memory:          configutil.GetEnvInt("ARGON2_MEMORY_KIB", 19*1024),
+		Argon2Time:            configutil.GetEnvInt("ARGON2_TIME", 2),
+		Argon2Threads:         configutil.GetEnvInt("ARGON2_THREADS", 1),
+
+		PasswordMinLength:            configutil.GetEnvInt("PASSWORD_MIN_LENGTH", 8),
+		PasswordMaxLength:            configutil.GetEnvInt("PASSWORD_MAX_LENGTH", 64),
+		PasswordRequireUpper:         configutil.GetEnvBool("PASSWORD_REQUIRE_UPPERCASE", false),
+		PasswordRequireLower:         configutil.GetEnvBool("PASSWORD_REQUIRE_LOWERCASE", false),
+		PasswordRequireDigit:         configutil.GetEnvBool("PASSWORD_REQUIRE_DIGIT", false),
+		PasswordRequireSymbol:        configutil.GetEnvBool("PASSWORD_REQUIRE_SYMBOL", false),
+		PasswordDisallowPersonalInfo: configutil.GetEnvBool("PASSWORD_DISALLOW_PERSONAL_INFO", true),
+		BreachedPasswordsFile:        configutil.GetEnv("BREACHED_PASSWORDS_FILE", ""),
+
+		AccountDeletionGracePeriod: configutil.GetEnvDuration("ACCOUNT_DELETION_GRACE_PERIOD", 30*24*time.Hour),
+		MoodURL:                    configutil.GetEnv("MOOD_URL", "http://localhost:3002"),
+		AdviceURL:                  configutil.GetEnv("ADVICE_URL", "http://localhost:3003"),
+
+		AuthEventRetention: configutil.GetEnvDuration("AUTH_EVENT_RETENTION", 90*24*time.Hour),
+
+		MagicLinkTTL:         configutil.GetEnvDuration("MAGIC_LINK_TTL", 15*time.Minute),
+		MagicLinkBindDevice:  configutil.GetEnvBool("MAGIC_LINK_BIND_DEVICE", true),
+		MagicLinkMaxPerEmail: configutil.GetEnvInt("MAGIC_LINK_MAX_PER_EMAIL", 3),
+		MagicLinkRateWindow:  configutil.GetEnvDuration("MAGIC_LINK_RATE_WINDOW", time.Hour),
+
+		OIDCProviders:       getOIDCProviders(),
+		OIDCRedirectBaseURL: configutil.GetEnv("OIDC_REDIRECT_BASE_URL", "http://localhost:3000"),
+		OIDCLoginTTL:        configutil.GetEnvDuration("OIDC_LOGIN_TTL", 10*time.Minute),
+	}
+}
+
+// Helper function to read the providers named in OIDC_PROVIDERS, each configured by OIDC_<NAME>_* variables
+func getOIDCProviders() []OIDCProvider {
+	providers := make([]OIDCProvider, 0)
+	for _, name := range strings.Split(configutil.GetEnv("OIDC_PROVIDERS", ""), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		prefix := "OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		providers = append(providers, OIDCProvider{
+			Name:             name,
+			Issuer:           configutil.GetEnv(prefix+"ISSUER", ""),
+			ClientID:         configutil.GetEnv(prefix+"CLIENT_ID", ""),
+			ClientSecret:     configutil.GetEnv(prefix+"CLIENT_SECRET", ""),
+			Scopes:           strings.Fields(configutil.GetEnv(prefix+"SCOPES", "openid email profile")),
+			AuthorizationURL: configutil.GetEnv(prefix+"AUTHORIZATION_URL", ""),
+		})
 	}
+	return providers
 }
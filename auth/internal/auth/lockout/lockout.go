@@ -0,0 +1,99 @@
+package lockout
+
+import (
+	"context"
+	"time"
+
+	"github.com/ciameksw/mood-api/auth/internal/auth/config"
+)
+
+// Store keeps failure counters and locks, shared by every instance when backed by Redis
+type Store interface {
+	// Incr increments the failure counter of key, keeping it for ttl after the last failure
+	Incr(ctx context.Context, key string, ttl time.Duration) (int, error)
+	// Lock locks key for d
+	Lock(ctx context.Context, key string, d time.Duration) error
+	// LockedFor returns how long key stays locked, zero when it is not locked
+	LockedFor(ctx context.Context, key string) (time.Duration, error)
+	// Reset clears the counter and lock of key
+	Reset(ctx context.Context, key string) error
+}
+
+// NewStore returns the store selected by the LOCKOUT_STORE setting
+func NewStore(cfg *config.Config) Store {
+	switch cfg.LockoutStore {
+	case "redis":
+		return NewRedisStore(cfg.RedisAddr, cfg.RedisPassword)
+	default:
+		return NewMemoryStore()
+	}
+}
+
+// Policy describes when failures turn into a lockout and how long it lasts
+type Policy struct {
+	// Number of failures that triggers the first lockout
+	Threshold int
+	// Lockout after reaching the threshold, doubled with every further failure
+	BaseLockout time.Duration
+	// Upper bound for a single lockout
+	MaxLockout time.Duration
+	// Failures are forgotten after this long without a new one
+	Window time.Duration
+}
+
+// Limiter applies a policy to keys in one namespace, e.g. accounts or client IPs
+type Limiter struct {
+	Store  Store
+	Policy Policy
+	Prefix string
+}
+
+func NewLimiter(store Store, prefix string, policy Policy) *Limiter {
+	return &Limiter{
+		Store:  store,
+		Policy: policy,
+		Prefix: prefix,
+	}
+}
+
+// Check returns how long key is locked, zero when attempts are allowed
+func (l *Limiter) Check(ctx context.Context, key string) (time.Duration, error) {
+	return l.Store.LockedFor(ctx, l.Prefix+key)
+}
+
+// Fail records a failed attempt and returns the lockout it caused, zero when still under the threshold
+func (l *Limiter) Fail(ctx context.Context, key string) (time.Duration, error) {
+	count, err := l.Store.Incr(ctx, l.Prefix+key, l.Policy.Window)
+	if err != nil {
+		return 0, err
+	}
+
+	if count < l.Policy.Threshold {
+		return 0, nil
+	}
+
+	d := l.lockoutFor(count)
+	if err := l.Store.Lock(ctx, l.Prefix+key, d); err != nil {
+		return 0, err
+	}
+
+	return d, nil
+}
+
+// Reset forgets the failures of key and lifts its lock
+func (l *Limiter) Reset(ctx context.Context, key string) error {
+	return l.Store.Reset(ctx, l.Prefix+key)
+}
+
+// Helper function computing the exponential backoff for the given failure count
+func (l *Limiter) lockoutFor(count int) time.Duration {
+	d := l.Policy.BaseLockout
+	for i := l.Policy.Threshold; i < count; i++ {
+		d *= 2
+		if d >= l.Policy.MaxLockout {
+			return l.Policy.MaxLockout
+		}
+	}
+
+	return min(d, l.Policy.MaxLockout)
+}
@@ -0,0 +1,104 @@
+package lockout
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// How often expired entries are swept from memory
+const memorySweepInterval = time.Minute
+
+type memoryEntry struct {
+	count       int
+	expiresAt   time.Time
+	lockedUntil time.Time
+}
+
+// MemoryStore keeps counters in process memory, suitable for a single instance
+type MemoryStore struct {
+	mu        sync.Mutex
+	entries   map[string]*memoryEntry
+	lastSweep time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		entries:   make(map[string]*memoryEntry),
+		lastSweep: time.Now(),
+	}
+}
+
+func (ms *MemoryStore) Incr(ctx context.Context, key string, ttl time.Duration) (int, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	now := time.Now()
+	ms.sweep(now)
+
+	entry := ms.entries[key]
+	if entry == nil || now.After(entry.expiresAt) {
+		entry = &memoryEntry{}
+		ms.entries[key] = entry
+	}
+
+	entry.count++
+	entry.expiresAt = now.Add(ttl)
+	return entry.count, nil
+}
+
+func (ms *MemoryStore) Lock(ctx context.Context, key string, d time.Duration) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	now := time.Now()
+	entry := ms.entries[key]
+	if entry == nil {
+		entry = &memoryEntry{}
+		ms.entries[key] = entry
+	}
+
+	entry.lockedUntil = now.Add(d)
+	if entry.expiresAt.Before(entry.lockedUntil) {
+		entry.expiresAt = entry.lockedUntil
+	}
+	return nil
+}
+
+func (ms *MemoryStore) LockedFor(ctx context.Context, key string) (time.Duration, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	entry := ms.entries[key]
+	if entry == nil {
+		return 0, nil
+	}
+
+	remaining := time.Until(entry.lockedUntil)
+	if remaining <= 0 {
+		return 0, nil
+	}
+	return remaining, nil
+}
+
+func (ms *MemoryStore) Reset(ctx context.Context, key string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	delete(ms.entries, key)
+	return nil
+}
+
+// Helper function to drop expired entries, the caller must hold the lock
+func (ms *MemoryStore) sweep(now time.Time) {
+	if now.Sub(ms.lastSweep) < memorySweepInterval {
+		return
+	}
+
+	for key, entry := range ms.entries {
+		if now.After(entry.expiresAt) {
+			delete(ms.entries, key)
+		}
+	}
+	ms.lastSweep = now
+}
@@ -0,0 +1,69 @@
+package lockout
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisStore keeps counters in Redis so every instance sees the same failures
+type RedisStore struct {
+	client *redis.Client
+}
+
+func NewRedisStore(addr, password string) *RedisStore {
+	client := redis.NewClient(&redis.Options{
+		Addr:     addr,
+		Password: password,
+	})
+	return &RedisStore{
+		client: client,
+	}
+}
+
+func (rs *RedisStore) Incr(ctx context.Context, key string, ttl time.Duration) (int, error) {
+	pipe := rs.client.TxPipeline()
+	incr := pipe.Incr(ctx, countKey(key))
+	pipe.PExpire(ctx, countKey(key), ttl)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+
+	return int(incr.Val()), nil
+}
+
+func (rs *RedisStore) Lock(ctx context.Context, key string, d time.Duration) error {
+	return rs.client.Set(ctx, lockKey(key), 1, d).Err()
+}
+
+func (rs *RedisStore) LockedFor(ctx context.Context, key string) (time.Duration, error) {
+	ttl, err := rs.client.PTTL(ctx, lockKey(key)).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	// Negative values mean the key does not exist or has no expiry
+	if ttl < 0 {
+		return 0, nil
+	}
+	return ttl, nil
+}
+
+func (rs *RedisStore) Reset(ctx context.Context, key string) error {
+	return rs.client.Del(ctx, countKey(key), lockKey(key)).Err()
+}
+
+// Close closes the Redis connection
+func (rs *RedisStore) Close() error {
+	return rs.client.Close()
+}
+
+func countKey(key string) string {
+	return "lockout:count:" + key
+}
+
+func lockKey(key string) string {
+	return "lockout:lock:" + key
+}
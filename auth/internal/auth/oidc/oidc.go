@@ -0,0 +1,330 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ciameksw/mood-api/auth/internal/auth/config"
+	"github.com/ciameksw/mood-api/pkg/jwks"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// Minimum time between JWKS fetches triggered by unknown kids
+	minKeysRefreshInterval = 30 * time.Second
+	// Allowed clock difference between us and the provider
+	clockSkew = time.Minute
+)
+
+// Algorithms accepted for ID token signatures, the key type has to match as well
+var signingMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+// Provider is an OpenID Connect identity provider users can sign in with
+type Provider struct {
+	Name             string
+	Issuer           string
+	ClientID         string
+	ClientSecret     string
+	Scopes           []string
+	RedirectURL      string
+	AuthorizationURL string
+
+	client *http.Client
+
+	mu            sync.RWMutex
+	metadata      *metadata
+	keys          map[string]crypto.PublicKey
+	keysFetchedAt time.Time
+
+	refreshMu sync.Mutex
+}
+
+// metadata is the part of the discovery document we use
+type metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Claims are the ID token claims used to find or create the local account
+type Claims struct {
+	Email             string       `json:"email"`
+	EmailVerified     flexibleBool `json:"email_verified"`
+	Name              string       `json:"name"`
+	PreferredUsername string       `json:"preferred_username"`
+	Nonce             string       `json:"nonce"`
+	jwt.RegisteredClaims
+}
+
+// NewProviders builds the configured providers keyed by name
+func NewProviders(cfg *config.Config) map[string]*Provider {
+	providers := make(map[string]*Provider, len(cfg.OIDCProviders))
+	for _, pc := range cfg.OIDCProviders {
+		providers[pc.Name] = &Provider{
+			Name:             pc.Name,
+			Issuer:           pc.Issuer,
+			ClientID:         pc.ClientID,
+			ClientSecret:     pc.ClientSecret,
+			Scopes:           pc.Scopes,
+			RedirectURL:      strings.TrimSuffix(cfg.OIDCRedirectBaseURL, "/") + "/auth/oidc/" + url.PathEscape(pc.Name) + "/callback",
+			AuthorizationURL: pc.AuthorizationURL,
+			client:           &http.Client{Timeout: 10 * time.Second},
+			keys:             make(map[string]crypto.PublicKey),
+		}
+	}
+	return providers
+}
+
+// CodeChallenge derives the S256 PKCE challenge sent with the authorization request from the verifier
+func CodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// AuthCodeURL returns the URL the user is sent to in order to sign in at the provider
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, codeVerifier string) (string, error) {
+	md, err := p.getMetadata(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	endpoint := md.AuthorizationEndpoint
+	if p.AuthorizationURL != "" {
+		endpoint = p.AuthorizationURL
+	}
+
+	q := url.Values{}
+	q.Set("response_type", "code")
+	q.Set("client_id", p.ClientID)
+	q.Set("redirect_uri", p.RedirectURL)
+	q.Set("scope", strings.Join(p.Scopes, " "))
+	q.Set("state", state)
+	q.Set("nonce", nonce)
+	q.Set("code_challenge", CodeChallenge(codeVerifier))
+	q.Set("code_challenge_method", "S256")
+
+	sep := "?"
+	if strings.Contains(endpoint, "?") {
+		sep = "&"
+	}
+	return endpoint + sep + q.Encode(), nil
+}
+
+// Exchange redeems the authorization code and returns the verified ID token claims
+func (p *Provider) Exchange(ctx context.Context, code, codeVerifier, nonce string) (*Claims, error) {
+	md, err := p.getMetadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.RedirectURL)
+	form.Set("code_verifier", codeVerifier)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, md.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(p.ClientID), url.QueryEscape(p.ClientSecret))
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body); err != nil {
+		return nil, fmt.Errorf("token endpoint returned %s: %w", resp.Status, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token endpoint returned %s: %s %s", resp.Status, body.Error, body.ErrorDescription)
+	}
+	if body.IDToken == "" {
+		return nil, errors.New("token endpoint returned no id_token")
+	}
+
+	return p.verifyIDToken(body.IDToken, nonce)
+}
+
+func (p *Provider) verifyIDToken(rawIDToken, nonce string) (*Claims, error) {
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(rawIDToken, claims, p.keyFunc,
+		jwt.WithValidMethods(signingMethods),
+		jwt.WithIssuer(p.Issuer),
+		jwt.WithAudience(p.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(clockSkew),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id token: %w", err)
+	}
+
+	if claims.Subject == "" {
+		return nil, errors.New("id token has no subject")
+	}
+
+	// The nonce ties the ID token to the login that was started here
+	if subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1 {
+		return nil, errors.New("id token nonce mismatch")
+	}
+
+	return claims, nil
+}
+
+func (p *Provider) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	if key, ok := p.getKey(kid); ok {
+		return key, nil
+	}
+
+	// The provider may have rotated its keys since the last fetch
+	if err := p.refreshKeysThrottled(); err != nil {
+		return nil, err
+	}
+
+	if key, ok := p.getKey(kid); ok {
+		return key, nil
+	}
+	return nil, errors.New("unknown signing key")
+}
+
+// Helper function to find a key by kid, tokens without a kid are accepted when the provider has a single key
+func (p *Provider) getKey(kid string) (crypto.PublicKey, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+
+	key, ok := p.keys[kid]
+	return key, ok
+}
+
+func (p *Provider) refreshKeysThrottled() error {
+	p.refreshMu.Lock()
+	defer p.refreshMu.Unlock()
+
+	p.mu.RLock()
+	recent := time.Since(p.keysFetchedAt) < minKeysRefreshInterval
+	md := p.metadata
+	p.mu.RUnlock()
+	if recent {
+		return nil
+	}
+	if md == nil {
+		return errors.New("provider metadata not loaded")
+	}
+
+	var set jwks.Set
+	if err := p.getJSON(context.Background(), md.JWKSURI, &set); err != nil {
+		return err
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		pub, err := k.PublicKey()
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = pub
+	}
+
+	p.mu.Lock()
+	p.keys = keys
+	p.keysFetchedAt = time.Now()
+	p.mu.Unlock()
+	return nil
+}
+
+// Helper function to load the discovery document once, failures are retried on the next login
+func (p *Provider) getMetadata(ctx context.Context) (*metadata, error) {
+	p.mu.RLock()
+	md := p.metadata
+	p.mu.RUnlock()
+	if md != nil {
+		return md, nil
+	}
+
+	md = &metadata{}
+	if err := p.getJSON(ctx, strings.TrimSuffix(p.Issuer, "/")+"/.well-known/openid-configuration", md); err != nil {
+		return nil, err
+	}
+
+	// A document for another issuer could hand out tokens we would then trust
+	if md.Issuer != p.Issuer {
+		return nil, fmt.Errorf("discovery document issuer %q does not match %q", md.Issuer, p.Issuer)
+	}
+	if md.AuthorizationEndpoint == "" || md.TokenEndpoint == "" || md.JWKSURI == "" {
+		return nil, errors.New("discovery document is missing endpoints")
+	}
+
+	p.mu.Lock()
+	p.metadata = md
+	p.mu.Unlock()
+	return md, nil
+}
+
+func (p *Provider) getJSON(ctx context.Context, u string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s from %s", resp.Status, u)
+	}
+
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
+
+// flexibleBool accepts both JSON booleans and the "true"/"false" strings some providers send
+type flexibleBool bool
+
+func (b *flexibleBool) UnmarshalJSON(data []byte) error {
+	switch strings.Trim(string(data), `"`) {
+	case "true":
+		*b = true
+	case "false", "null", "":
+		*b = false
+	default:
+		return fmt.Errorf("invalid boolean %s", data)
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+package passwordpolicy
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/ciameksw/mood-api/auth/internal/auth/config"
+)
+
+// Shorter usernames or email local parts would reject too many unrelated passwords
+const minPersonalInfoLength = 3
+
+// Rules describes what a new password has to satisfy
+type Rules struct {
+	MinLength     int
+	MaxLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	// Reject passwords containing the username or the email address
+	DisallowPersonalInfo bool
+}
+
+// Policy checks new passwords against the rules and a list of known breached passwords
+type Policy struct {
+	Rules Rules
+	// Breached passwords, lowercased plain text or uppercase hex SHA-1 as in the Have I Been Pwned downloads
+	breached     map[string]struct{}
+	breachedSHA1 map[string]struct{}
+}
+
+// NewPolicy builds the policy from the PASSWORD_* settings and loads the breached password list if one is configured
+func NewPolicy(cfg *config.Config) (*Policy, error) {
+	rules := Rules{
+		MinLength:            cfg.PasswordMinLength,
+		MaxLength:            cfg.PasswordMaxLength,
+		RequireUpper:         cfg.PasswordRequireUpper,
+		RequireLower:         cfg.PasswordRequireLower,
+		RequireDigit:         cfg.PasswordRequireDigit,
+		RequireSymbol:        cfg.PasswordRequireSymbol,
+		DisallowPersonalInfo: cfg.PasswordDisallowPersonalInfo,
+	}
+	if rules.MinLength < 1 || rules.MaxLength < rules.MinLength {
+		return nil, fmt.Errorf("password length limits %d-%d are invalid", rules.MinLength, rules.MaxLength)
+	}
+
+	p := &Policy{
+		Rules:        rules,
+		breached:     make(map[string]struct{}),
+		breachedSHA1: make(map[string]struct{}),
+	}
+	if cfg.BreachedPasswordsFile != "" {
+		if err := p.loadBreached(cfg.BreachedPasswordsFile); err != nil {
+			return nil, err
+		}
+	}
+
+	return p, nil
+}
+
+// BreachedCount returns the number of loaded breached passwords
+func (p *Policy) BreachedCount() int {
+	return len(p.breached) + len(p.breachedSHA1)
+}
+
+// Check returns why a password is rejected for the user with the given username and email, nil when it is accepted
+func (p *Policy) Check(password, username, email string) []string {
+	var problems []string
+
+	length := utf8.RuneCountInString(password)
+	if length < p.Rules.MinLength {
+		problems = append(problems, fmt.Sprintf("must be at least %d characters", p.Rules.MinLength))
+	}
+	if length > p.Rules.MaxLength {
+		problems = append(problems, fmt.Sprintf("must be at most %d characters", p.Rules.MaxLength))
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case !unicode.IsLetter(r) && !unicode.IsSpace(r):
+			hasSymbol = true
+		}
+	}
+	if p.Rules.RequireUpper && !hasUpper {
+		problems = append(problems, "must contain an uppercase letter")
+	}
+	if p.Rules.RequireLower && !hasLower {
+		problems = append(problems, "must contain a lowercase letter")
+	}
+	if p.Rules.RequireDigit && !hasDigit {
+		problems = append(problems, "must contain a digit")
+	}
+	if p.Rules.RequireSymbol && !hasSymbol {
+		problems = append(problems, "must contain a symbol")
+	}
+
+	if p.Rules.DisallowPersonalInfo {
+		lower := strings.ToLower(password)
+		if len(username) >= minPersonalInfoLength && strings.Contains(lower, strings.ToLower(username)) {
+			problems = append(problems, "must not contain your username")
+		}
+		local, _, _ := strings.Cut(email, "@")
+		if len(local) >= minPersonalInfoLength && strings.Contains(lower, strings.ToLower(local)) {
+			problems = append(problems, "must not contain your email address")
+		}
+	}
+
+	if p.isBreached(password) {
+		problems = append(problems, "appears in a list of breached passwords, choose a different one")
+	}
+
+	return problems
+}
+
+func (p *Policy) isBreached(password string) bool {
+	if _, ok := p.breached[strings.ToLower(password)]; ok {
+		return true
+	}
+	sum := sha1.Sum([]byte(password))
+	_, ok := p.breachedSHA1[strings.ToUpper(hex.EncodeToString(sum[:]))]
+	return ok
+}
+
+// Helper function to read one password per line, lines holding a SHA-1 hash with an optional ":count" are stored as hashes
+func (p *Policy) loadBreached(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open breached password list: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if hash, _, _ := strings.Cut(line, ":"); isSHA1Hex(hash) {
+			p.breachedSHA1[strings.ToUpper(hash)] = struct{}{}
+			continue
+		}
+		p.breached[strings.ToLower(line)] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read breached password list: %w", err)
+	}
+
+	return nil
+}
+
+func isSHA1Hex(s string) bool {
+	if len(s) != sha1.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
@@ -0,0 +1,195 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+// CreateAccessToken records an issued access token so it can be revoked later
+func (o *DBOperations) CreateAccessToken(ctx context.Context, jti string, userID int, familyID string, issuedAt, expiresAt time.Time) error {
+	query := "INSERT INTO access_tokens (jti, user_id, family_id, issued_at, expires_at) VALUES ($1, $2, $3, $4, $5)"
+	_, err := o.Postgres.DB.ExecContext(ctx, query, jti, userID, familyID, issuedAt, expiresAt)
+	return err
+}
+
+// IsAccessTokenActive checks that an access token was issued by us and has not been revoked
+func (o *DBOperations) IsAccessTokenActive(ctx context.Context, jti string) (bool, error) {
+	var active bool
+	query := "SELECT EXISTS(SELECT 1 FROM access_tokens WHERE jti = $1 AND revoked_at IS NULL)"
+
+	err := o.Postgres.DB.QueryRowContext(ctx, query, jti).Scan(&active)
+	if err != nil {
+		return false, err
+	}
+
+	return active, nil
+}
+
+// GetAccessTokenFamily returns the refresh token family the access token was issued with
+func (o *DBOperations) GetAccessTokenFamily(ctx context.Context, jti string) (string, error) {
+	var familyID string
+	query := "SELECT family_id FROM access_tokens WHERE jti = $1"
+
+	err := o.Postgres.DB.QueryRowContext(ctx, query, jti).Scan(&familyID)
+	if err != nil {
+		return "", err
+	}
+
+	return familyID, nil
+}
+
+// RevokeAccessToken revokes a single access token
+func (o *DBOperations) RevokeAccessToken(ctx context.Context, jti string) error {
+	query := "UPDATE access_tokens SET revoked_at = $1 WHERE jti = $2 AND revoked_at IS NULL"
+	_, err := o.Postgres.DB.ExecContext(ctx, query, time.Now(), jti)
+	return err
+}
+
+// RevokeUserTokens revokes every outstanding access, refresh and personal access token and every session of a user
+func (o *DBOperations) RevokeUserTokens(ctx context.Context, userID int) error {
+	tx, err := o.Postgres.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	_, err = tx.ExecContext(ctx, "UPDATE access_tokens SET revoked_at = $1 WHERE user_id = $2 AND revoked_at IS NULL AND expires_at > $1", now, userID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE refresh_tokens SET revoked_at = $1 WHERE user_id = $2 AND revoked_at IS NULL", now, userID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE sessions SET revoked_at = $1 WHERE user_id = $2 AND revoked_at IS NULL", now, userID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE personal_access_tokens SET revoked_at = $1 WHERE user_id = $2 AND revoked_at IS NULL", now, userID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// RevokeOtherUserTokens revokes every token and session of a user except the session keepSessionID,
+// personal access tokens are revoked as well since they do not belong to any session
+func (o *DBOperations) RevokeOtherUserTokens(ctx context.Context, userID int, keepSessionID string) error {
+	tx, err := o.Postgres.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	_, err = tx.ExecContext(ctx, "UPDATE access_tokens SET revoked_at = $1 WHERE user_id = $2 AND family_id <> $3 AND revoked_at IS NULL AND expires_at > $1", now, userID, keepSessionID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE refresh_tokens SET revoked_at = $1 WHERE user_id = $2 AND family_id <> $3 AND revoked_at IS NULL", now, userID, keepSessionID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE sessions SET revoked_at = $1 WHERE user_id = $2 AND id <> $3 AND revoked_at IS NULL", now, userID, keepSessionID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE personal_access_tokens SET revoked_at = $1 WHERE user_id = $2 AND revoked_at IS NULL", now, userID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DeleteExpiredTokens removes access and refresh tokens, sessions, pending OIDC logins and login links that can no longer be used
+func (o *DBOperations) DeleteExpiredTokens(ctx context.Context) (int64, error) {
+	now := time.Now()
+
+	result, err := o.Postgres.DB.ExecContext(ctx, "DELETE FROM access_tokens WHERE expires_at < $1", now)
+	if err != nil {
+		return 0, err
+	}
+	accessDeleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	result, err = o.Postgres.DB.ExecContext(ctx, "DELETE FROM refresh_tokens WHERE expires_at < $1", now)
+	if err != nil {
+		return 0, err
+	}
+	refreshDeleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	result, err = o.Postgres.DB.ExecContext(ctx, "DELETE FROM sessions WHERE expires_at < $1", now)
+	if err != nil {
+		return 0, err
+	}
+	sessionsDeleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	result, err = o.Postgres.DB.ExecContext(ctx, "DELETE FROM oidc_login_states WHERE expires_at < $1", now)
+	if err != nil {
+		return 0, err
+	}
+	oidcStatesDeleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	result, err = o.Postgres.DB.ExecContext(ctx, "DELETE FROM magic_link_tokens WHERE expires_at < $1", now)
+	if err != nil {
+		return 0, err
+	}
+	magicLinksDeleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return accessDeleted + refreshDeleted + sessionsDeleted + oidcStatesDeleted + magicLinksDeleted, nil
+}
+
+type RevokedToken struct {
+	JTI       string    `json:"jti"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	RevokedAt time.Time `json:"revokedAt"`
+}
+
+// GetRevokedTokensSince lists unexpired tokens revoked after the given time
+func (o *DBOperations) GetRevokedTokensSince(ctx context.Context, since time.Time) ([]RevokedToken, error) {
+	revoked := make([]RevokedToken, 0)
+	query := "SELECT jti, expires_at, revoked_at FROM access_tokens WHERE revoked_at > $1 AND expires_at > $2 ORDER BY revoked_at"
+
+	rows, err := o.Postgres.DB.QueryContext(ctx, query, since, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var rt RevokedToken
+		if err := rows.Scan(&rt.JTI, &rt.ExpiresAt, &rt.RevokedAt); err != nil {
+			return nil, err
+		}
+		revoked = append(revoked, rt)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return revoked, nil
+}
@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+const (
+	DeletionRequested   = "requested"
+	DeletionRestored    = "restored"
+	DeletionPurgeFailed = "purge_failed"
+	DeletionPurged      = "purged"
+)
+
+// ScheduleUserDeletion marks a user as deleted until purgeAfter, the restore token hash lets the owner undo it until then
+func (o *DBOperations) ScheduleUserDeletion(ctx context.Context, userID int, restoreTokenHash string, purgeAfter time.Time) error {
+	tx, err := o.Postgres.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := "UPDATE users SET deleted_at = $1, purge_after = $2, restore_token_hash = $3 WHERE id = $4 AND deleted_at IS NULL"
+	result, err := tx.ExecContext(ctx, query, time.Now(), purgeAfter, restoreTokenHash, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return errors.New("user not found")
+	}
+
+	if err := addDeletionAudit(ctx, tx, userID, DeletionRequested, &userID, "purge after "+purgeAfter.UTC().Format(time.RFC3339)); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetUserIDByRestoreToken finds the deleted user a restore token belongs to while the grace period lasts
+func (o *DBOperations) GetUserIDByRestoreToken(ctx context.Context, restoreTokenHash string) (int, error) {
+	var userID int
+	query := "SELECT id FROM users WHERE restore_token_hash = $1 AND deleted_at IS NOT NULL AND purge_after > $2"
+
+	err := o.Postgres.DB.QueryRowContext(ctx, query, restoreTokenHash, time.Now()).Scan(&userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, errors.New("restore token not found")
+		}
+		return 0, err
+	}
+
+	return userID, nil
+}
+
+// RestoreUser cancels a scheduled deletion, actorID is the user or admin restoring the account
+func (o *DBOperations) RestoreUser(ctx context.Context, userID, actorID int) error {
+	tx, err := o.Postgres.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := "UPDATE users SET deleted_at = NULL, purge_after = NULL, restore_token_hash = NULL WHERE id = $1 AND deleted_at IS NOT NULL AND purge_after > $2"
+	result, err := tx.ExecContext(ctx, query, userID, time.Now())
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return errors.New("user not scheduled for deletion")
+	}
+
+	if err := addDeletionAudit(ctx, tx, userID, DeletionRestored, &actorID, ""); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetUsersDueForPurge lists deleted users whose grace period has ended, oldest first
+func (o *DBOperations) GetUsersDueForPurge(ctx context.Context, limit int) ([]int, error) {
+	userIDs := make([]int, 0)
+	query := "SELECT id FROM users WHERE deleted_at IS NOT NULL AND purge_after <= $1 ORDER BY purge_after LIMIT $2"
+
+	rows, err := o.Postgres.DB.QueryContext(ctx, query, time.Now(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var userID int
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return userIDs, nil
+}
+
+// PurgeUser deletes a user whose grace period has ended, tokens, sessions and other auth data go with it
+func (o *DBOperations) PurgeUser(ctx context.Context, userID int) error {
+	tx, err := o.Postgres.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := "DELETE FROM users WHERE id = $1 AND deleted_at IS NOT NULL AND purge_after <= $2"
+	result, err := tx.ExecContext(ctx, query, userID, time.Now())
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return errors.New("user not due for purge")
+	}
+
+	if err := addDeletionAudit(ctx, tx, userID, DeletionPurged, nil, ""); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// AddDeletionAudit records a step of an account deletion that happens outside the other operations
+func (o *DBOperations) AddDeletionAudit(ctx context.Context, userID int, action, detail string) error {
+	query := "INSERT INTO account_deletion_audit (user_id, action, detail, created_at) VALUES ($1, $2, $3, $4)"
+
+	_, err := o.Postgres.DB.ExecContext(ctx, query, userID, action, detail, time.Now())
+	return err
+}
+
+// Helper function to record a deletion step in the transaction that performs it
+func addDeletionAudit(ctx context.Context, tx *sql.Tx, userID int, action string, actorID *int, detail string) error {
+	query := "INSERT INTO account_deletion_audit (user_id, action, actor_id, detail, created_at) VALUES ($1, $2, $3, $4, $5)"
+
+	_, err := tx.ExecContext(ctx, query, userID, action, actorID, sql.NullString{String: detail, Valid: detail != ""}, time.Now())
+	return err
+}
@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+type EmailVerificationToken struct {
+	ID        int
+	UserID    int
+	Email     string
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+}
+
+// CreateEmailVerificationToken stores a verification token hash, invalidating the user's previous unused tokens
+func (o *DBOperations) CreateEmailVerificationToken(ctx context.Context, userID int, email, tokenHash string, expiresAt time.Time) error {
+	tx, err := o.Postgres.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	_, err = tx.ExecContext(ctx, "UPDATE email_verification_tokens SET used_at = $1 WHERE user_id = $2 AND used_at IS NULL", now, userID)
+	if err != nil {
+		return err
+	}
+
+	query := "INSERT INTO email_verification_tokens (user_id, email, token_hash, expires_at, created_at) VALUES ($1, $2, $3, $4, $5)"
+	_, err = tx.ExecContext(ctx, query, userID, email, tokenHash, expiresAt, now)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetEmailVerificationToken retrieves a verification token by its hash
+func (o *DBOperations) GetEmailVerificationToken(ctx context.Context, tokenHash string) (*EmailVerificationToken, error) {
+	evt := &EmailVerificationToken{}
+	var usedAt sql.NullTime
+	query := "SELECT id, user_id, email, expires_at, used_at FROM email_verification_tokens WHERE token_hash = $1"
+
+	err := o.Postgres.DB.QueryRowContext(ctx, query, tokenHash).Scan(&evt.ID, &evt.UserID, &evt.Email, &evt.ExpiresAt, &usedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("verification token not found")
+		}
+		return nil, err
+	}
+
+	if usedAt.Valid {
+		evt.UsedAt = &usedAt.Time
+	}
+
+	return evt, nil
+}
+
+// GetLastEmailVerificationSentAt returns when the user's latest verification token was created
+func (o *DBOperations) GetLastEmailVerificationSentAt(ctx context.Context, userID int) (*time.Time, error) {
+	var sentAt sql.NullTime
+	query := "SELECT MAX(created_at) FROM email_verification_tokens WHERE user_id = $1"
+
+	err := o.Postgres.DB.QueryRowContext(ctx, query, userID).Scan(&sentAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if !sentAt.Valid {
+		return nil, nil
+	}
+	return &sentAt.Time, nil
+}
+
+// VerifyEmail marks the verification token as used and the user's email as verified
+func (o *DBOperations) VerifyEmail(ctx context.Context, evt *EmailVerificationToken) error {
+	tx, err := o.Postgres.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	result, err := tx.ExecContext(ctx, "UPDATE email_verification_tokens SET used_at = $1 WHERE id = $2 AND used_at IS NULL", now, evt.ID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return errors.New("verification token already used")
+	}
+
+	// The token only verifies the address it was sent to
+	result, err = tx.ExecContext(ctx, "UPDATE users SET verified_at = $1 WHERE id = $2 AND email = $3", now, evt.UserID, evt.Email)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err = result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return errors.New("email has changed")
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,159 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	EventRegister                 = "register"
+	EventLogin                    = "login"
+	EventLoginLocked              = "login_locked"
+	EventTwoFactorLogin           = "two_factor_login"
+	EventOIDCLogin                = "oidc_login"
+	EventOIDCLinked               = "oidc_linked"
+	EventMagicLinkRequested       = "magic_link_requested"
+	EventMagicLinkLogin           = "magic_link_login"
+	EventLogout                   = "logout"
+	EventLogoutAll                = "logout_all"
+	EventSessionRevoked           = "session_revoked"
+	EventPasswordResetRequested   = "password_reset_requested"
+	EventPasswordReset            = "password_reset"
+	EventPasswordChanged          = "password_changed"
+	EventEmailChanged             = "email_changed"
+	EventUsernameChanged          = "username_changed"
+	EventEmailVerified            = "email_verified"
+	EventTwoFactorEnabled         = "two_factor_enabled"
+	EventTwoFactorDisabled        = "two_factor_disabled"
+	EventRecoveryCodesRegenerated = "recovery_codes_regenerated"
+	EventPersonalTokenCreated     = "personal_token_created"
+	EventPersonalTokenRevoked     = "personal_token_revoked"
+	EventRolesChanged             = "roles_changed"
+	EventDeletionRequested        = "account_deletion_requested"
+	EventAccountRestored          = "account_restored"
+	EventAccountPurged            = "account_purged"
+
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+type AuthEvent struct {
+	ID        int64     `json:"id"`
+	UserID    *int      `json:"userId,omitempty"`
+	Type      string    `json:"type"`
+	Outcome   string    `json:"outcome"`
+	IPAddress string    `json:"ipAddress,omitempty"`
+	UserAgent string    `json:"userAgent,omitempty"`
+	Detail    string    `json:"detail,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// AuthEventFilter narrows an event query, zero values match everything
+type AuthEventFilter struct {
+	UserID    int
+	Type      string
+	Outcome   string
+	IPAddress string
+	From      time.Time
+	To        time.Time
+	Limit     int
+	Offset    int
+}
+
+// AddAuthEvent appends an event to the audit log
+func (o *DBOperations) AddAuthEvent(ctx context.Context, e AuthEvent) error {
+	query := "INSERT INTO auth_events (user_id, event_type, outcome, ip_address, user_agent, detail, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7)"
+
+	_, err := o.Postgres.DB.ExecContext(ctx, query,
+		e.UserID,
+		e.Type,
+		e.Outcome,
+		sql.NullString{String: e.IPAddress, Valid: e.IPAddress != ""},
+		sql.NullString{String: e.UserAgent, Valid: e.UserAgent != ""},
+		sql.NullString{String: e.Detail, Valid: e.Detail != ""},
+		time.Now(),
+	)
+	return err
+}
+
+// GetAuthEvents lists the events matching the filter, newest first
+func (o *DBOperations) GetAuthEvents(ctx context.Context, f AuthEventFilter) ([]AuthEvent, error) {
+	events := make([]AuthEvent, 0)
+
+	conditions := []string{}
+	args := []interface{}{}
+	addCondition := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if f.UserID != 0 {
+		addCondition("user_id = $%d", f.UserID)
+	}
+	if f.Type != "" {
+		addCondition("event_type = $%d", f.Type)
+	}
+	if f.Outcome != "" {
+		addCondition("outcome = $%d", f.Outcome)
+	}
+	if f.IPAddress != "" {
+		addCondition("ip_address = $%d", f.IPAddress)
+	}
+	if !f.From.IsZero() {
+		addCondition("created_at >= $%d", f.From)
+	}
+	if !f.To.IsZero() {
+		addCondition("created_at < $%d", f.To)
+	}
+
+	query := "SELECT id, user_id, event_type, outcome, ip_address, user_agent, detail, created_at FROM auth_events"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	args = append(args, f.Limit, f.Offset)
+	query += fmt.Sprintf(" ORDER BY created_at DESC, id DESC LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+
+	rows, err := o.Postgres.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e AuthEvent
+		var userID sql.NullInt64
+		var ipAddress, userAgent, detail sql.NullString
+		if err := rows.Scan(&e.ID, &userID, &e.Type, &e.Outcome, &ipAddress, &userAgent, &detail, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		if userID.Valid {
+			id := int(userID.Int64)
+			e.UserID = &id
+		}
+		e.IPAddress = ipAddress.String
+		e.UserAgent = userAgent.String
+		e.Detail = detail.String
+		events = append(events, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// DeleteAuthEventsBefore removes events older than the retention period and returns how many were removed
+func (o *DBOperations) DeleteAuthEventsBefore(ctx context.Context, before time.Time) (int64, error) {
+	query := "DELETE FROM auth_events WHERE created_at < $1"
+
+	result, err := o.Postgres.DB.ExecContext(ctx, query, before)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
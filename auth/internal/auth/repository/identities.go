@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type OIDCLoginState struct {
+	Provider     string
+	Nonce        string
+	CodeVerifier string
+	ExpiresAt    time.Time
+}
+
+// CreateOIDCLoginState stores a pending login until the provider redirects back
+func (o *DBOperations) CreateOIDCLoginState(ctx context.Context, stateHash, provider, nonce, codeVerifier string, expiresAt time.Time) error {
+	query := "INSERT INTO oidc_login_states (state_hash, provider, nonce, code_verifier, expires_at, created_at) VALUES ($1, $2, $3, $4, $5, $6)"
+
+	_, err := o.Postgres.DB.ExecContext(ctx, query, stateHash, provider, nonce, codeVerifier, expiresAt, time.Now())
+	return err
+}
+
+// ConsumeOIDCLoginState removes and returns a pending login so its state cannot be replayed
+func (o *DBOperations) ConsumeOIDCLoginState(ctx context.Context, stateHash string) (*OIDCLoginState, error) {
+	ls := &OIDCLoginState{}
+	query := "DELETE FROM oidc_login_states WHERE state_hash = $1 RETURNING provider, nonce, code_verifier, expires_at"
+
+	err := o.Postgres.DB.QueryRowContext(ctx, query, stateHash).Scan(&ls.Provider, &ls.Nonce, &ls.CodeVerifier, &ls.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("login state not found")
+		}
+		return nil, err
+	}
+
+	return ls, nil
+}
+
+// GetUserIDByIdentity finds the user linked to a provider subject and records the login
+func (o *DBOperations) GetUserIDByIdentity(ctx context.Context, provider, subject string) (int, error) {
+	var userID int
+	query := "UPDATE user_identities SET last_login_at = $1 WHERE provider = $2 AND subject = $3 RETURNING user_id"
+
+	err := o.Postgres.DB.QueryRowContext(ctx, query, time.Now(), provider, subject).Scan(&userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, errors.New("identity not found")
+		}
+		return 0, err
+	}
+
+	return userID, nil
+}
+
+// LinkUserIdentity links a provider subject to an existing user
+func (o *DBOperations) LinkUserIdentity(ctx context.Context, userID int, provider, subject, email string) error {
+	now := time.Now()
+	query := "INSERT INTO user_identities (user_id, provider, subject, email, created_at, last_login_at) VALUES ($1, $2, $3, $4, $5, $5)"
+
+	_, err := o.Postgres.DB.ExecContext(ctx, query, userID, provider, subject, email, now)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return errors.New("identity already linked")
+		}
+		return err
+	}
+
+	return nil
+}
+
+// CreateUserWithIdentity creates a user without a password whose email the provider has verified, linked to the provider subject
+func (o *DBOperations) CreateUserWithIdentity(ctx context.Context, username, email, provider, subject string) (int, error) {
+	tx, err := o.Postgres.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	var userID int
+	// An empty hash never matches a password, so the account can only sign in through the provider until a password is set
+	insertUser := "INSERT INTO users (username, email, password_hash, created_at, verified_at) VALUES ($1, $2, '', $3, $3) RETURNING id"
+	err = tx.QueryRowContext(ctx, insertUser, username, email, now).Scan(&userID)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return 0, errors.New("user already exists")
+		}
+		return 0, err
+	}
+
+	insertIdentity := "INSERT INTO user_identities (user_id, provider, subject, email, created_at, last_login_at) VALUES ($1, $2, $3, $4, $5, $5)"
+	_, err = tx.ExecContext(ctx, insertIdentity, userID, provider, subject, email, now)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return 0, errors.New("identity already linked")
+		}
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return userID, nil
+}
+
+// Helper function to detect unique constraint violations
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+type MagicLinkToken struct {
+	ID         int
+	UserID     int
+	Email      string
+	DeviceHash string
+	ExpiresAt  time.Time
+	UsedAt     *time.Time
+}
+
+// CreateMagicLinkToken stores a login link token hash bound to a device, invalidating the user's previous unused links
+func (o *DBOperations) CreateMagicLinkToken(ctx context.Context, userID int, email, tokenHash, deviceHash string, expiresAt time.Time) error {
+	tx, err := o.Postgres.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	_, err = tx.ExecContext(ctx, "UPDATE magic_link_tokens SET used_at = $1 WHERE user_id = $2 AND used_at IS NULL", now, userID)
+	if err != nil {
+		return err
+	}
+
+	query := "INSERT INTO magic_link_tokens (user_id, email, token_hash, device_hash, expires_at, created_at) VALUES ($1, $2, $3, $4, $5, $6)"
+	_, err = tx.ExecContext(ctx, query, userID, email, tokenHash, deviceHash, expiresAt, now)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetMagicLinkToken retrieves a login link token by its hash
+func (o *DBOperations) GetMagicLinkToken(ctx context.Context, tokenHash string) (*MagicLinkToken, error) {
+	mlt := &MagicLinkToken{}
+	var usedAt sql.NullTime
+	query := "SELECT id, user_id, email, device_hash, expires_at, used_at FROM magic_link_tokens WHERE token_hash = $1"
+
+	err := o.Postgres.DB.QueryRowContext(ctx, query, tokenHash).Scan(&mlt.ID, &mlt.UserID, &mlt.Email, &mlt.DeviceHash, &mlt.ExpiresAt, &usedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("magic link not found")
+		}
+		return nil, err
+	}
+
+	if usedAt.Valid {
+		mlt.UsedAt = &usedAt.Time
+	}
+
+	return mlt, nil
+}
+
+// UseMagicLinkToken marks the link as used. Opening it proves control of the mailbox, so an unverified address becomes verified.
+func (o *DBOperations) UseMagicLinkToken(ctx context.Context, mlt *MagicLinkToken) error {
+	tx, err := o.Postgres.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	result, err := tx.ExecContext(ctx, "UPDATE magic_link_tokens SET used_at = $1 WHERE id = $2 AND used_at IS NULL", now, mlt.ID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return errors.New("magic link already used")
+	}
+
+	// The link was sent to an address the account may no longer have
+	result, err = tx.ExecContext(ctx, "UPDATE users SET verified_at = COALESCE(verified_at, $1) WHERE id = $2 AND email = $3", now, mlt.UserID, mlt.Email)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err = result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return errors.New("email changed")
+	}
+
+	return tx.Commit()
+}
@@ -20,32 +20,26 @@ type User struct {
 	Email        string
 	PasswordHash string
 	CreatedAt    time.Time
+	VerifiedAt   *time.Time
+	// Set while the account is scheduled for deletion
+	DeletedAt *time.Time
 }
 
-// CreateUser inserts a new user into the database
-func (o *DBOperations) CreateUser(ctx context.Context, username, email, passwordHash string) (int, error) {
-	var userID int
-	query := "INSERT INTO users (username, email, password_hash, created_at) VALUES ($1, $2, $3, $4) RETURNING id"
-
-	err := o.Postgres.DB.QueryRowContext(ctx, query, username, email, passwordHash, time.Now()).Scan(&userID)
-	if err != nil {
-		return 0, err
-	}
-
-	return userID, nil
-}
+const userColumns = "id, username, email, password_hash, created_at, verified_at, deleted_at"
 
-// GetUserByEmail retrieves a user by email
-func (o *DBOperations) GetUserByEmail(ctx context.Context, email string) (*User, error) {
+// Helper function to scan a row selected with userColumns
+func scanUser(row *sql.Row) (*User, error) {
 	user := &User{}
-	query := "SELECT id, username, email, password_hash, created_at FROM users WHERE email = $1"
+	var verifiedAt, deletedAt sql.NullTime
 
-	err := o.Postgres.DB.QueryRowContext(ctx, query, email).Scan(
+	err := row.Scan(
 		&user.ID,
 		&user.Username,
 		&user.Email,
 		&user.PasswordHash,
 		&user.CreatedAt,
+		&verifiedAt,
+		&deletedAt,
 	)
 
 	if err != nil {
@@ -55,9 +49,35 @@ func (o *DBOperations) GetUserByEmail(ctx context.Context, email string) (*User,
 		return nil, err
 	}
 
+	if verifiedAt.Valid {
+		user.VerifiedAt = &verifiedAt.Time
+	}
+	if deletedAt.Valid {
+		user.DeletedAt = &deletedAt.Time
+	}
+
 	return user, nil
 }
 
+// CreateUser inserts a new user into the database
+func (o *DBOperations) CreateUser(ctx context.Context, username, email, passwordHash string) (int, error) {
+	var userID int
+	query := "INSERT INTO users (username, email, password_hash, created_at) VALUES ($1, $2, $3, $4) RETURNING id"
+
+	err := o.Postgres.DB.QueryRowContext(ctx, query, username, email, passwordHash, time.Now()).Scan(&userID)
+	if err != nil {
+		return 0, err
+	}
+
+	return userID, nil
+}
+
+// GetUserByEmail retrieves a user by email
+func (o *DBOperations) GetUserByEmail(ctx context.Context, email string) (*User, error) {
+	query := "SELECT " + userColumns + " FROM users WHERE email = $1"
+	return scanUser(o.Postgres.DB.QueryRowContext(ctx, query, email))
+}
+
 // UserExistsByEmail checks if a user with the given email exists
 func (o *DBOperations) UserExistsByEmail(ctx context.Context, email string) (bool, error) {
 	var exists bool
@@ -86,52 +106,18 @@ func (o *DBOperations) UserExistsByUsername(ctx context.Context, username string
 
 // GetUserByID retrieves a user by ID
 func (o *DBOperations) GetUserByID(ctx context.Context, userID int) (*User, error) {
-	user := &User{}
-	query := "SELECT id, username, email, password_hash, created_at FROM users WHERE id = $1"
-
-	err := o.Postgres.DB.QueryRowContext(ctx, query, userID).Scan(
-		&user.ID,
-		&user.Username,
-		&user.Email,
-		&user.PasswordHash,
-		&user.CreatedAt,
-	)
-
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, errors.New("user not found")
-		}
-		return nil, err
-	}
-
-	return user, nil
+	query := "SELECT " + userColumns + " FROM users WHERE id = $1"
+	return scanUser(o.Postgres.DB.QueryRowContext(ctx, query, userID))
 }
 
 // GetUserByUsername retrieves a user by username
 func (o *DBOperations) GetUserByUsername(ctx context.Context, username string) (*User, error) {
-	user := &User{}
-	query := "SELECT id, username, email, password_hash, created_at FROM users WHERE username = $1"
-
-	err := o.Postgres.DB.QueryRowContext(ctx, query, username).Scan(
-		&user.ID,
-		&user.Username,
-		&user.Email,
-		&user.PasswordHash,
-		&user.CreatedAt,
-	)
-
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, errors.New("user not found")
-		}
-		return nil, err
-	}
-
-	return user, nil
+	query := "SELECT " + userColumns + " FROM users WHERE username = $1"
+	return scanUser(o.Postgres.DB.QueryRowContext(ctx, query, username))
 }
 
 // UpdateUser updates user profile data
-func (o *DBOperations) UpdateUser(ctx context.Context, userID int, username, email string, passwordHash *string) error {
+func (o *DBOperations) UpdateUser(ctx context.Context, userID int, username, email string) error {
 	query := "UPDATE users SET "
 	args := []interface{}{}
 	argIndex := 1
@@ -145,17 +131,15 @@ func (o *DBOperations) UpdateUser(ctx context.Context, userID int, username, ema
 	}
 
 	if email != "" {
-		updates = append(updates, fmt.Sprintf("email = $%d", argIndex))
+		// A new address has to be verified again, the CASE sees the email before the update
+		updates = append(updates,
+			fmt.Sprintf("email = $%d", argIndex),
+			fmt.Sprintf("verified_at = CASE WHEN email = $%d THEN verified_at ELSE NULL END", argIndex),
+		)
 		args = append(args, email)
 		argIndex++
 	}
 
-	if passwordHash != nil {
-		updates = append(updates, fmt.Sprintf("password_hash = $%d", argIndex))
-		args = append(args, *passwordHash)
-		argIndex++
-	}
-
 	if len(updates) == 0 {
 		return errors.New("no fields to update")
 	}
@@ -167,10 +151,11 @@ func (o *DBOperations) UpdateUser(ctx context.Context, userID int, username, ema
 	return err
 }
 
-// DeleteUser deletes a user from the database
-func (o *DBOperations) DeleteUser(ctx context.Context, userID int) error {
-	query := "DELETE FROM users WHERE id = $1"
-	result, err := o.Postgres.DB.ExecContext(ctx, query, userID)
+// UpdatePassword sets a new password hash
+func (o *DBOperations) UpdatePassword(ctx context.Context, userID int, passwordHash string) error {
+	query := "UPDATE users SET password_hash = $1 WHERE id = $2"
+
+	result, err := o.Postgres.DB.ExecContext(ctx, query, passwordHash, userID)
 	if err != nil {
 		return err
 	}
@@ -179,7 +164,6 @@ func (o *DBOperations) DeleteUser(ctx context.Context, userID int) error {
 	if err != nil {
 		return err
 	}
-
 	if rowsAffected == 0 {
 		return errors.New("user not found")
 	}
@@ -187,6 +171,14 @@ func (o *DBOperations) DeleteUser(ctx context.Context, userID int) error {
 	return nil
 }
 
+// ReplacePasswordHash swaps the password hash unless it changed since oldHash was read
+func (o *DBOperations) ReplacePasswordHash(ctx context.Context, userID int, oldHash, newHash string) error {
+	query := "UPDATE users SET password_hash = $1 WHERE id = $2 AND password_hash = $3"
+
+	_, err := o.Postgres.DB.ExecContext(ctx, query, newHash, userID, oldHash)
+	return err
+}
+
 // Helper function to build UPDATE query
 func buildUpdateQuery(updates []string, nextArgIndex int) string {
 	query := ""
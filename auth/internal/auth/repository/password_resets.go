@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+type PasswordResetToken struct {
+	ID        int
+	UserID    int
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+}
+
+// CreatePasswordResetToken stores a reset token hash, invalidating the user's previous unused tokens
+func (o *DBOperations) CreatePasswordResetToken(ctx context.Context, userID int, tokenHash string, expiresAt time.Time) error {
+	tx, err := o.Postgres.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	_, err = tx.ExecContext(ctx, "UPDATE password_reset_tokens SET used_at = $1 WHERE user_id = $2 AND used_at IS NULL", now, userID)
+	if err != nil {
+		return err
+	}
+
+	query := "INSERT INTO password_reset_tokens (user_id, token_hash, expires_at, created_at) VALUES ($1, $2, $3, $4)"
+	_, err = tx.ExecContext(ctx, query, userID, tokenHash, expiresAt, now)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetPasswordResetToken retrieves a reset token by its hash
+func (o *DBOperations) GetPasswordResetToken(ctx context.Context, tokenHash string) (*PasswordResetToken, error) {
+	prt := &PasswordResetToken{}
+	var usedAt sql.NullTime
+	query := "SELECT id, user_id, expires_at, used_at FROM password_reset_tokens WHERE token_hash = $1"
+
+	err := o.Postgres.DB.QueryRowContext(ctx, query, tokenHash).Scan(&prt.ID, &prt.UserID, &prt.ExpiresAt, &usedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("reset token not found")
+		}
+		return nil, err
+	}
+
+	if usedAt.Valid {
+		prt.UsedAt = &usedAt.Time
+	}
+
+	return prt, nil
+}
+
+// ResetPassword marks the reset token as used and stores the new password hash
+func (o *DBOperations) ResetPassword(ctx context.Context, prt *PasswordResetToken, passwordHash string) error {
+	tx, err := o.Postgres.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, "UPDATE password_reset_tokens SET used_at = $1 WHERE id = $2 AND used_at IS NULL", time.Now(), prt.ID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return errors.New("reset token already used")
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE users SET password_hash = $1 WHERE id = $2", passwordHash, prt.UserID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
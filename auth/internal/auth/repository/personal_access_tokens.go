@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type PersonalAccessToken struct {
+	ID         int        `json:"id"`
+	UserID     int        `json:"-"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  time.Time  `json:"expiresAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+const personalAccessTokenColumns = "id, user_id, name, scopes, expires_at, last_used_at, created_at"
+
+// Helper function to scan a row selected with personalAccessTokenColumns
+func scanPersonalAccessToken(scan func(dest ...any) error) (*PersonalAccessToken, error) {
+	pat := &PersonalAccessToken{}
+	var lastUsedAt sql.NullTime
+
+	err := scan(&pat.ID, &pat.UserID, &pat.Name, pq.Array(&pat.Scopes), &pat.ExpiresAt, &lastUsedAt, &pat.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if lastUsedAt.Valid {
+		pat.LastUsedAt = &lastUsedAt.Time
+	}
+
+	return pat, nil
+}
+
+// CreatePersonalAccessToken stores a new personal access token hash
+func (o *DBOperations) CreatePersonalAccessToken(ctx context.Context, userID int, name, tokenHash string, scopes []string, expiresAt time.Time) (*PersonalAccessToken, error) {
+	query := "INSERT INTO personal_access_tokens (user_id, name, token_hash, scopes, expires_at, created_at) VALUES ($1, $2, $3, $4, $5, $6) RETURNING " + personalAccessTokenColumns
+
+	row := o.Postgres.DB.QueryRowContext(ctx, query, userID, name, tokenHash, pq.Array(scopes), expiresAt, time.Now())
+	return scanPersonalAccessToken(row.Scan)
+}
+
+// GetUserPersonalAccessTokens lists the unrevoked personal access tokens of a user, newest first
+func (o *DBOperations) GetUserPersonalAccessTokens(ctx context.Context, userID int) ([]PersonalAccessToken, error) {
+	tokens := make([]PersonalAccessToken, 0)
+	query := "SELECT " + personalAccessTokenColumns + " FROM personal_access_tokens WHERE user_id = $1 AND revoked_at IS NULL ORDER BY created_at DESC"
+
+	rows, err := o.Postgres.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		pat, err := scanPersonalAccessToken(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, *pat)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+// GetActivePersonalAccessToken retrieves an unrevoked and unexpired personal access token by its hash
+func (o *DBOperations) GetActivePersonalAccessToken(ctx context.Context, tokenHash string) (*PersonalAccessToken, error) {
+	query := "SELECT " + personalAccessTokenColumns + " FROM personal_access_tokens WHERE token_hash = $1 AND revoked_at IS NULL AND expires_at > $2"
+
+	pat, err := scanPersonalAccessToken(o.Postgres.DB.QueryRowContext(ctx, query, tokenHash, time.Now()).Scan)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("personal access token not found")
+		}
+		return nil, err
+	}
+
+	return pat, nil
+}
+
+// TouchPersonalAccessToken records that a token was used, at most once a minute
+func (o *DBOperations) TouchPersonalAccessToken(ctx context.Context, id int) error {
+	now := time.Now()
+	query := "UPDATE personal_access_tokens SET last_used_at = $1 WHERE id = $2 AND (last_used_at IS NULL OR last_used_at < $3)"
+
+	_, err := o.Postgres.DB.ExecContext(ctx, query, now, id, now.Add(-time.Minute))
+	return err
+}
+
+// RevokePersonalAccessToken revokes a personal access token owned by the user
+func (o *DBOperations) RevokePersonalAccessToken(ctx context.Context, id, userID int) error {
+	query := "UPDATE personal_access_tokens SET revoked_at = $1 WHERE id = $2 AND user_id = $3 AND revoked_at IS NULL"
+
+	result, err := o.Postgres.DB.ExecContext(ctx, query, time.Now(), id, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return errors.New("personal access token not found")
+	}
+
+	return nil
+}
@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/ciameksw/mood-api/pkg/localtime"
+)
+
+type UserPreferences struct {
+	Timezone        string `json:"timezone"`
+	Locale          string `json:"locale"`
+	WeekStart       string `json:"weekStart"`
+	ReminderEnabled bool   `json:"reminderEnabled"`
+	ReminderTime    string `json:"reminderTime"`
+}
+
+// DefaultUserPreferences are used until a user saves their own
+func DefaultUserPreferences() UserPreferences {
+	return UserPreferences{
+		Timezone:     localtime.DefaultTimezone,
+		Locale:       "en",
+		WeekStart:    localtime.DefaultWeekStart,
+		ReminderTime: "20:00",
+	}
+}
+
+// GetUserPreferences returns the preferences of a user, or the defaults when none were saved
+func (o *DBOperations) GetUserPreferences(ctx context.Context, userID int) (UserPreferences, error) {
+	var p UserPreferences
+	query := "SELECT timezone, locale, week_start, reminder_enabled, reminder_time FROM user_preferences WHERE user_id = $1"
+
+	err := o.Postgres.DB.QueryRowContext(ctx, query, userID).Scan(&p.Timezone, &p.Locale, &p.WeekStart, &p.ReminderEnabled, &p.ReminderTime)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return DefaultUserPreferences(), nil
+		}
+		return UserPreferences{}, err
+	}
+
+	return p, nil
+}
+
+// SaveUserPreferences stores the preferences of a user, replacing the previous ones
+func (o *DBOperations) SaveUserPreferences(ctx context.Context, userID int, p UserPreferences) error {
+	query := `INSERT INTO user_preferences (user_id, timezone, locale, week_start, reminder_enabled, reminder_time, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (user_id) DO UPDATE SET timezone = EXCLUDED.timezone, locale = EXCLUDED.locale, week_start = EXCLUDED.week_start,
+			reminder_enabled = EXCLUDED.reminder_enabled, reminder_time = EXCLUDED.reminder_time, updated_at = EXCLUDED.updated_at`
+
+	_, err := o.Postgres.DB.ExecContext(ctx, query, userID, p.Timezone, p.Locale, p.WeekStart, p.ReminderEnabled, p.ReminderTime, time.Now())
+	return err
+}
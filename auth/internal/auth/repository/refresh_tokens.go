@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+type RefreshToken struct {
+	ID        int
+	UserID    int
+	FamilyID  string
+	TokenHash string
+	ExpiresAt time.Time
+	RevokedAt *time.Time
+	CreatedAt time.Time
+}
+
+// CreateRefreshToken stores a new refresh token hash
+func (o *DBOperations) CreateRefreshToken(ctx context.Context, userID int, familyID, tokenHash string, expiresAt time.Time) (int, error) {
+	var id int
+	query := "INSERT INTO refresh_tokens (user_id, family_id, token_hash, expires_at, created_at) VALUES ($1, $2, $3, $4, $5) RETURNING id"
+
+	err := o.Postgres.DB.QueryRowContext(ctx, query, userID, familyID, tokenHash, expiresAt, time.Now()).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// GetRefreshTokenByHash retrieves a refresh token by its hash
+func (o *DBOperations) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (*RefreshToken, error) {
+	rt := &RefreshToken{}
+	var revokedAt sql.NullTime
+	query := "SELECT id, user_id, family_id, token_hash, expires_at, revoked_at, created_at FROM refresh_tokens WHERE token_hash = $1"
+
+	err := o.Postgres.DB.QueryRowContext(ctx, query, tokenHash).Scan(
+		&rt.ID,
+		&rt.UserID,
+		&rt.FamilyID,
+		&rt.TokenHash,
+		&rt.ExpiresAt,
+		&revokedAt,
+		&rt.CreatedAt,
+	)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("refresh token not found")
+		}
+		return nil, err
+	}
+
+	if revokedAt.Valid {
+		rt.RevokedAt = &revokedAt.Time
+	}
+
+	return rt, nil
+}
+
+// RotateRefreshToken revokes the given refresh token and stores its replacement in the same family
+func (o *DBOperations) RotateRefreshToken(ctx context.Context, old *RefreshToken, newTokenHash string, expiresAt time.Time) error {
+	tx, err := o.Postgres.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	result, err := tx.ExecContext(ctx, "UPDATE refresh_tokens SET revoked_at = $1 WHERE id = $2 AND revoked_at IS NULL", now, old.ID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	// Another request rotated this token first
+	if rowsAffected == 0 {
+		return errors.New("refresh token already used")
+	}
+
+	var newID int
+	insert := "INSERT INTO refresh_tokens (user_id, family_id, token_hash, expires_at, created_at) VALUES ($1, $2, $3, $4, $5) RETURNING id"
+	err = tx.QueryRowContext(ctx, insert, old.UserID, old.FamilyID, newTokenHash, expiresAt, now).Scan(&newID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE refresh_tokens SET replaced_by = $1 WHERE id = $2", newID, old.ID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// RevokeRefreshTokenFamily revokes every active refresh token in a family together with the access tokens issued from it
+// and the session the family belongs to
+func (o *DBOperations) RevokeRefreshTokenFamily(ctx context.Context, familyID string) error {
+	tx, err := o.Postgres.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	_, err = tx.ExecContext(ctx, "UPDATE refresh_tokens SET revoked_at = $1 WHERE family_id = $2 AND revoked_at IS NULL", now, familyID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE access_tokens SET revoked_at = $1 WHERE family_id = $2 AND revoked_at IS NULL AND expires_at > $1", now, familyID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE sessions SET revoked_at = $1 WHERE id = $2 AND revoked_at IS NULL", now, familyID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type UserWithRoles struct {
+	ID            int       `json:"id"`
+	Username      string    `json:"username"`
+	Email         string    `json:"email"`
+	EmailVerified bool      `json:"emailVerified"`
+	Roles         []string  `json:"roles"`
+	CreatedAt     time.Time `json:"createdAt"`
+	// Set while the account is scheduled for deletion
+	DeletedAt *time.Time `json:"deletedAt,omitempty"`
+}
+
+// GetUserRoles lists the roles assigned to a user
+func (o *DBOperations) GetUserRoles(ctx context.Context, userID int) ([]string, error) {
+	roles := make([]string, 0)
+	query := "SELECT role FROM user_roles WHERE user_id = $1 ORDER BY role"
+
+	rows, err := o.Postgres.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var role string
+		if err := rows.Scan(&role); err != nil {
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return roles, nil
+}
+
+// AddUserRole assigns a role to a user, assigning a role the user already has is a no-op
+func (o *DBOperations) AddUserRole(ctx context.Context, userID int, role string) error {
+	query := "INSERT INTO user_roles (user_id, role, created_at) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING"
+
+	_, err := o.Postgres.DB.ExecContext(ctx, query, userID, role, time.Now())
+	return err
+}
+
+// SetUserRoles replaces the roles of a user
+func (o *DBOperations) SetUserRoles(ctx context.Context, userID int, roles []string) error {
+	tx, err := o.Postgres.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM user_roles WHERE user_id = $1 AND NOT (role = ANY($2))", userID, pq.Array(roles))
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, role := range roles {
+		_, err = tx.ExecContext(ctx, "INSERT INTO user_roles (user_id, role, created_at) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING", userID, role, now)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetUsersWithRoles lists all users together with their roles, oldest account first
+func (o *DBOperations) GetUsersWithRoles(ctx context.Context) ([]UserWithRoles, error) {
+	users := make([]UserWithRoles, 0)
+	query := `SELECT u.id, u.username, u.email, u.verified_at, u.created_at, u.deleted_at,
+			COALESCE(array_agg(r.role ORDER BY r.role) FILTER (WHERE r.role IS NOT NULL), '{}')
+		FROM users u
+		LEFT JOIN user_roles r ON r.user_id = u.id
+		GROUP BY u.id
+		ORDER BY u.id`
+
+	rows, err := o.Postgres.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var u UserWithRoles
+		var verifiedAt, deletedAt sql.NullTime
+		if err := rows.Scan(&u.ID, &u.Username, &u.Email, &verifiedAt, &u.CreatedAt, &deletedAt, pq.Array(&u.Roles)); err != nil {
+			return nil, err
+		}
+		u.EmailVerified = verifiedAt.Valid
+		if deletedAt.Valid {
+			u.DeletedAt = &deletedAt.Time
+		}
+		users = append(users, u)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+type Session struct {
+	ID         string    `json:"id"`
+	UserID     int       `json:"-"`
+	UserAgent  string    `json:"userAgent"`
+	IPAddress  string    `json:"ipAddress"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastSeenAt time.Time `json:"lastSeenAt"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+}
+
+// CreateSession records a new login
+func (o *DBOperations) CreateSession(ctx context.Context, id string, userID int, userAgent, ipAddress string, expiresAt time.Time) error {
+	now := time.Now()
+	query := "INSERT INTO sessions (id, user_id, user_agent, ip_address, created_at, last_seen_at, expires_at) VALUES ($1, $2, $3, $4, $5, $5, $6)"
+
+	_, err := o.Postgres.DB.ExecContext(ctx, query, id, userID, userAgent, ipAddress, now, expiresAt)
+	return err
+}
+
+// GetUserSessions lists the active sessions of a user, most recently used first
+func (o *DBOperations) GetUserSessions(ctx context.Context, userID int) ([]Session, error) {
+	sessions := make([]Session, 0)
+	query := `SELECT id, user_id, user_agent, ip_address, created_at, last_seen_at, expires_at FROM sessions
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > $2
+		ORDER BY last_seen_at DESC`
+
+	rows, err := o.Postgres.DB.QueryContext(ctx, query, userID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var s Session
+		if err := rows.Scan(&s.ID, &s.UserID, &s.UserAgent, &s.IPAddress, &s.CreatedAt, &s.LastSeenAt, &s.ExpiresAt); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, s)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+// GetSessionOwner returns the user of an active session
+func (o *DBOperations) GetSessionOwner(ctx context.Context, id string) (int, error) {
+	var userID int
+	query := "SELECT user_id FROM sessions WHERE id = $1 AND revoked_at IS NULL AND expires_at > $2"
+
+	err := o.Postgres.DB.QueryRowContext(ctx, query, id, time.Now()).Scan(&userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, errors.New("session not found")
+		}
+		return 0, err
+	}
+
+	return userID, nil
+}
+
+// TouchSession updates the last seen time of a session.
+// Writes are skipped when the session was seen within the last minute to keep authorize cheap.
+func (o *DBOperations) TouchSession(ctx context.Context, id string) error {
+	now := time.Now()
+	query := "UPDATE sessions SET last_seen_at = $1 WHERE id = $2 AND revoked_at IS NULL AND last_seen_at < $3"
+
+	_, err := o.Postgres.DB.ExecContext(ctx, query, now, id, now.Add(-time.Minute))
+	return err
+}
+
+// ExtendSession records a token refresh, keeping the latest client details
+func (o *DBOperations) ExtendSession(ctx context.Context, id, userAgent, ipAddress string, expiresAt time.Time) error {
+	query := `UPDATE sessions SET last_seen_at = $1, expires_at = $2,
+			user_agent = COALESCE(NULLIF($3, ''), user_agent),
+			ip_address = COALESCE(NULLIF($4, ''), ip_address)
+		WHERE id = $5 AND revoked_at IS NULL`
+
+	_, err := o.Postgres.DB.ExecContext(ctx, query, time.Now(), expiresAt, userAgent, ipAddress, id)
+	return err
+}
@@ -0,0 +1,182 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+type TwoFactor struct {
+	UserID          int
+	SecretEncrypted string
+	ConfirmedAt     *time.Time
+	LastUsedStep    int64
+}
+
+// GetTwoFactor retrieves the two-factor settings of a user
+func (o *DBOperations) GetTwoFactor(ctx context.Context, userID int) (*TwoFactor, error) {
+	tf := &TwoFactor{}
+	var confirmedAt sql.NullTime
+	query := "SELECT user_id, secret_encrypted, confirmed_at, last_used_step FROM user_two_factor WHERE user_id = $1"
+
+	err := o.Postgres.DB.QueryRowContext(ctx, query, userID).Scan(&tf.UserID, &tf.SecretEncrypted, &confirmedAt, &tf.LastUsedStep)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("two factor not found")
+		}
+		return nil, err
+	}
+
+	if confirmedAt.Valid {
+		tf.ConfirmedAt = &confirmedAt.Time
+	}
+
+	return tf, nil
+}
+
+// SavePendingTwoFactor stores a new unconfirmed secret, replacing an earlier pending one
+func (o *DBOperations) SavePendingTwoFactor(ctx context.Context, userID int, secretEncrypted string) error {
+	query := `INSERT INTO user_two_factor (user_id, secret_encrypted, created_at) VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE SET secret_encrypted = EXCLUDED.secret_encrypted, last_used_step = 0, created_at = EXCLUDED.created_at
+		WHERE user_two_factor.confirmed_at IS NULL`
+
+	result, err := o.Postgres.DB.ExecContext(ctx, query, userID, secretEncrypted, time.Now())
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return errors.New("two factor already enabled")
+	}
+
+	return nil
+}
+
+// ConfirmTwoFactor enables two-factor authentication and stores the recovery code hashes
+func (o *DBOperations) ConfirmTwoFactor(ctx context.Context, userID int, step int64, recoveryCodeHashes []string) error {
+	tx, err := o.Postgres.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	result, err := tx.ExecContext(ctx, "UPDATE user_two_factor SET confirmed_at = $1, last_used_step = $2 WHERE user_id = $3 AND confirmed_at IS NULL", now, step, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return errors.New("two factor already enabled")
+	}
+
+	err = replaceRecoveryCodes(ctx, tx, userID, recoveryCodeHashes, now)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ReplaceRecoveryCodes invalidates the user's recovery codes and stores new ones
+func (o *DBOperations) ReplaceRecoveryCodes(ctx context.Context, userID int, recoveryCodeHashes []string) error {
+	tx, err := o.Postgres.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = replaceRecoveryCodes(ctx, tx, userID, recoveryCodeHashes, time.Now())
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UseTOTPStep records an accepted TOTP time step, failing when that step or a later one was already used
+func (o *DBOperations) UseTOTPStep(ctx context.Context, userID int, step int64) error {
+	query := "UPDATE user_two_factor SET last_used_step = $1 WHERE user_id = $2 AND last_used_step < $1"
+
+	result, err := o.Postgres.DB.ExecContext(ctx, query, step, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return errors.New("code already used")
+	}
+
+	return nil
+}
+
+// UseRecoveryCode marks an unused recovery code as used
+func (o *DBOperations) UseRecoveryCode(ctx context.Context, userID int, codeHash string) error {
+	query := "UPDATE recovery_codes SET used_at = $1 WHERE user_id = $2 AND code_hash = $3 AND used_at IS NULL"
+
+	result, err := o.Postgres.DB.ExecContext(ctx, query, time.Now(), userID, codeHash)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return errors.New("recovery code not found")
+	}
+
+	return nil
+}
+
+// DeleteTwoFactor disables two-factor authentication and removes the recovery codes
+func (o *DBOperations) DeleteTwoFactor(ctx context.Context, userID int) error {
+	tx, err := o.Postgres.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM recovery_codes WHERE user_id = $1", userID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM user_two_factor WHERE user_id = $1", userID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Helper function to swap the recovery codes of a user inside a transaction
+func replaceRecoveryCodes(ctx context.Context, tx *sql.Tx, userID int, codeHashes []string, now time.Time) error {
+	_, err := tx.ExecContext(ctx, "DELETE FROM recovery_codes WHERE user_id = $1", userID)
+	if err != nil {
+		return err
+	}
+
+	for _, codeHash := range codeHashes {
+		_, err = tx.ExecContext(ctx, "INSERT INTO recovery_codes (user_id, code_hash, created_at) VALUES ($1, $2, $3)", userID, codeHash, now)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
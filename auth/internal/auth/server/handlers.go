@@ -7,6 +7,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/ciameksw/mood-api/auth/internal/auth/repository"
 	"github.com/ciameksw/mood-api/auth/internal/auth/token"
 	"github.com/ciameksw/mood-api/pkg/httputil"
 )
@@ -14,7 +15,7 @@ import (
 type registerInput struct {
 	UserName string `json:"username" validate:"required,min=3,max=30"`
 	Email    string `json:"email" validate:"required,email"`
-	Password string `json:"password" validate:"required,min=8"`
+	Password string `json:"password" validate:"required"`
 }
 
 func (s *Server) handleRegister(w http.ResponseWriter, r *http.Request) {
@@ -29,7 +30,11 @@ func (s *Server) handleRegister(w http.ResponseWriter, r *http.Request) {
 
 	err = s.Validator.Struct(input)
 	if err != nil {
-		httputil.HandleError(*s.Logger, w, err.Error(), err, http.StatusBadRequest)
+		httputil.HandleValidationError(*s.Logger, w, err)
+		return
+	}
+
+	if !s.checkPasswordPolicy(w, input.Password, input.UserName, input.Email) {
 		return
 	}
 
@@ -44,7 +49,7 @@ func (s *Server) handleRegister(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	hashedPassword, err := token.HashPassword(input.Password)
+	hashedPassword, err := s.Passwords.Hash(input.Password)
 	if err != nil {
 		httputil.HandleError(*s.Logger, w, "Failed to hash password", err, http.StatusInternalServerError)
 		return
@@ -56,6 +61,9 @@ func (s *Server) handleRegister(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	s.sendVerificationAsync(userID)
+	s.recordEvent(r, userID, repository.EventRegister, repository.OutcomeSuccess, "")
+
 	s.Logger.Info.Printf("User registered successfully id: %d, username: %s, email: %s", userID, input.UserName, input.Email)
 	httputil.WriteSuccessMessage(*s.Logger, w, "User registered successfully", http.StatusCreated)
 }
@@ -66,7 +74,8 @@ type loginInput struct {
 }
 
 type loginResponse struct {
-	Token string `json:"token"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refreshToken"`
 }
 
 func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
@@ -81,13 +90,24 @@ func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
 
 	err = s.Validator.Struct(input)
 	if err != nil {
-		httputil.HandleError(*s.Logger, w, err.Error(), err, http.StatusBadRequest)
+		httputil.HandleValidationError(*s.Logger, w, err)
+		return
+	}
+
+	// Locked out attempts are rejected before the expensive password check
+	ip := clientIP(r)
+	if !s.checkLoginLockout(r.Context(), w, input.Email, ip) {
+		s.recordEvent(r, 0, repository.EventLoginLocked, repository.OutcomeFailure, "email: "+input.Email)
 		return
 	}
 
 	user, err := s.DBOperations.GetUserByEmail(r.Context(), input.Email)
 	if err != nil {
 		if err.Error() == "user not found" {
+			// Unknown emails take as long as wrong passwords so the timing does not reveal registered accounts
+			s.Passwords.VerifyDummy(input.Password)
+			s.recordLoginFailure(r.Context(), input.Email, ip)
+			s.recordEvent(r, 0, repository.EventLogin, repository.OutcomeFailure, "unknown email: "+input.Email)
 			httputil.HandleError(*s.Logger, w, "Invalid email or password", nil, http.StatusUnauthorized)
 			return
 		}
@@ -101,43 +121,111 @@ func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	match := token.VerifyPassword(input.Password, user.PasswordHash)
+	match, needsRehash := s.Passwords.Verify(input.Password, user.PasswordHash)
 	if !match {
-		httputil.HandleError(*s.Logger, w, "Invalid username or password", err, http.StatusUnauthorized)
+		s.recordLoginFailure(r.Context(), input.Email, ip)
+		s.recordEvent(r, user.ID, repository.EventLogin, repository.OutcomeFailure, "invalid password")
+		httputil.HandleError(*s.Logger, w, "Invalid email or password", nil, http.StatusUnauthorized)
 		return
 	}
 
-	token, err := token.GenerateJWT(user.ID, s.Config.Salt)
-	if err != nil {
-		httputil.HandleError(*s.Logger, w, "Failed to generate JWT", err, http.StatusInternalServerError)
+	if needsRehash {
+		s.rehashPassword(r.Context(), user, input.Password)
+	}
+
+	if s.Config.RequireEmailVerification && user.VerifiedAt == nil {
+		s.recordEvent(r, user.ID, repository.EventLogin, repository.OutcomeFailure, "email not verified")
+		httputil.HandleError(*s.Logger, w, "Email address is not verified", nil, http.StatusForbidden)
 		return
 	}
 
-	s.Logger.Info.Printf("User logged in: %v", user.Username)
-	resp := loginResponse{
-		Token: token,
+	if s.completeLogin(w, r, user, repository.EventLogin, "") {
+		s.resetAccountLockout(r.Context(), user.Email)
+	}
+}
+
+// Helper function to finish a login once the first factor succeeded. Users with two-factor authentication
+// get a challenge token, everyone else gets tokens. The outcome is recorded as eventType. Reports whether tokens were issued.
+func (s *Server) completeLogin(w http.ResponseWriter, r *http.Request, user *repository.User, eventType, detail string) bool {
+	if user.DeletedAt != nil {
+		s.recordEvent(r, user.ID, eventType, repository.OutcomeFailure, joinDetail(detail, "account scheduled for deletion"))
+		httputil.HandleError(*s.Logger, w, "Account is scheduled for deletion, use the link in the deletion email to restore it", nil, http.StatusForbidden)
+		return false
+	}
+
+	twoFactorEnabled, err := s.isTwoFactorEnabled(r.Context(), user.ID)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to check two-factor authentication", err, http.StatusInternalServerError)
+		return false
 	}
+
+	// The first factor alone only earns a challenge token that must be exchanged together with a code
+	if twoFactorEnabled {
+		challengeToken, err := token.GenerateChallengeJWT(user.ID, token.PurposeTwoFactor, s.Config.TwoFactorChallengeTTL, s.Keys)
+		if err != nil {
+			httputil.HandleError(*s.Logger, w, "Failed to generate challenge token", err, http.StatusInternalServerError)
+			return false
+		}
+
+		s.recordEvent(r, user.ID, eventType, repository.OutcomeSuccess, joinDetail(detail, "two-factor challenge issued"))
+		s.Logger.Info.Printf("Two-factor challenge issued: %v", user.Username)
+		resp := twoFactorChallengeResponse{
+			TwoFactorRequired: true,
+			ChallengeToken:    challengeToken,
+		}
+		httputil.WriteData(*s.Logger, w, resp, http.StatusOK)
+		return false
+	}
+
+	resp, err := s.issueTokens(r, user.ID)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to generate tokens", err, http.StatusInternalServerError)
+		return false
+	}
+
+	s.recordEvent(r, user.ID, eventType, repository.OutcomeSuccess, detail)
+	s.Logger.Info.Printf("User logged in: %v", user.Username)
 	httputil.WriteData(*s.Logger, w, resp, http.StatusOK)
+	return true
 }
 
 func (s *Server) handleAuthorize(w http.ResponseWriter, r *http.Request) {
-	userID, err := s.getUserIDFromToken(r)
+	// Personal access tokens are only accepted here, account endpoints require a login
+	tokenString := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if token.IsPersonalAccessToken(tokenString) {
+		s.authorizePersonalToken(w, r, tokenString)
+		return
+	}
+
+	claims, err := s.getClaimsFromToken(r)
 	if err != nil {
 		httputil.HandleError(*s.Logger, w, err.Error(), nil, http.StatusUnauthorized)
 		return
 	}
 
+	if claims.SessionID != "" {
+		if err := s.DBOperations.TouchSession(r.Context(), claims.SessionID); err != nil {
+			s.Logger.Error.Printf("Failed to update session %s: %v", claims.SessionID, err)
+		}
+	}
+
 	resp := map[string]interface{}{
-		"userId": userID,
+		"userId":    claims.UserID,
+		"sessionId": claims.SessionID,
+		"tokenType": "access",
+		"roles":     claims.Roles,
+		"timezone":  claims.Timezone,
+		"weekStart": claims.WeekStart,
 	}
 	httputil.WriteData(*s.Logger, w, resp, http.StatusOK)
 }
 
 type userResponse struct {
-	ID        int       `json:"id"`
-	Username  string    `json:"username"`
-	Email     string    `json:"email"`
-	CreatedAt time.Time `json:"createdAt"`
+	ID            int       `json:"id"`
+	Username      string    `json:"username"`
+	Email         string    `json:"email"`
+	EmailVerified bool      `json:"emailVerified"`
+	CreatedAt     time.Time `json:"createdAt"`
 }
 
 func (s *Server) handleGetUser(w http.ResponseWriter, r *http.Request) {
@@ -161,18 +249,21 @@ func (s *Server) handleGetUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	resp := userResponse{
-		ID:        user.ID,
-		Username:  user.Username,
-		Email:     user.Email,
-		CreatedAt: user.CreatedAt,
+		ID:            user.ID,
+		Username:      user.Username,
+		Email:         user.Email,
+		EmailVerified: user.VerifiedAt != nil,
+		CreatedAt:     user.CreatedAt,
 	}
 	httputil.WriteData(*s.Logger, w, resp, http.StatusOK)
 }
 
 type updateUserInput struct {
-	Username string `json:"username" validate:"omitempty,min=3,max=30"`
-	Email    string `json:"email" validate:"omitempty,email"`
-	Password string `json:"password" validate:"omitempty,min=8"`
+	Username        string `json:"username" validate:"omitempty,min=3,max=30"`
+	Email           string `json:"email" validate:"omitempty,email"`
+	CurrentPassword string `json:"currentPassword"`
+	// Password is only read to refuse it, passwords are changed with POST /auth/password/change
+	Password *string `json:"password"`
 }
 
 func (s *Server) handleUpdateUser(w http.ResponseWriter, r *http.Request) {
@@ -191,13 +282,22 @@ func (s *Server) handleUpdateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Clients written for the old API must not take a 200 as a changed password
+	if input.Password != nil {
+		httputil.WriteValidationErrors(*s.Logger, w, []httputil.FieldError{
+			{Field: "password", Message: "use POST /auth/password/change"},
+		})
+		return
+	}
+
 	err = s.Validator.Struct(input)
 	if err != nil {
-		httputil.HandleError(*s.Logger, w, err.Error(), err, http.StatusBadRequest)
+		httputil.HandleValidationError(*s.Logger, w, err)
 		return
 	}
 
 	// Check if new email already exists
+	emailChanged := false
 	if input.Email != "" {
 		existingUser, err := s.DBOperations.GetUserByEmail(r.Context(), input.Email)
 		if err != nil && err.Error() != "user not found" {
@@ -208,9 +308,11 @@ func (s *Server) handleUpdateUser(w http.ResponseWriter, r *http.Request) {
 			httputil.HandleError(*s.Logger, w, "Email already in use", nil, http.StatusConflict)
 			return
 		}
+		emailChanged = existingUser == nil
 	}
 
 	// Check if new username already exists
+	usernameChanged := false
 	if input.Username != "" {
 		existingUser, err := s.DBOperations.GetUserByUsername(r.Context(), input.Username)
 		if err != nil && err.Error() != "user not found" {
@@ -221,20 +323,30 @@ func (s *Server) handleUpdateUser(w http.ResponseWriter, r *http.Request) {
 			httputil.HandleError(*s.Logger, w, "Username already in use", nil, http.StatusConflict)
 			return
 		}
+		usernameChanged = existingUser == nil
 	}
 
-	// Hash password if provided
-	var hashedPassword *string
-	if input.Password != "" {
-		hashed, err := token.HashPassword(input.Password)
+	// Password resets go to the email address, so changing it takes the current password
+	if emailChanged {
+		if input.CurrentPassword == "" {
+			httputil.WriteValidationErrors(*s.Logger, w, []httputil.FieldError{
+				{Field: "currentPassword", Message: "is required to change the email"},
+			})
+			return
+		}
+
+		user, err := s.DBOperations.GetUserByID(r.Context(), userID)
 		if err != nil {
-			httputil.HandleError(*s.Logger, w, "Failed to hash password", err, http.StatusInternalServerError)
+			httputil.HandleError(*s.Logger, w, "Failed to retrieve user", err, http.StatusInternalServerError)
+			return
+		}
+
+		if !s.checkCurrentPassword(w, r, user, input.CurrentPassword, repository.EventEmailChanged) {
 			return
 		}
-		hashedPassword = &hashed
 	}
 
-	err = s.DBOperations.UpdateUser(r.Context(), userID, input.Username, input.Email, hashedPassword)
+	err = s.DBOperations.UpdateUser(r.Context(), userID, input.Username, input.Email)
 	if err != nil {
 		if err.Error() == "no fields to update" {
 			httputil.HandleError(*s.Logger, w, "No fields to update", nil, http.StatusBadRequest)
@@ -244,41 +356,50 @@ func (s *Server) handleUpdateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// The new address is unverified until the user confirms it
+	if emailChanged {
+		s.sendVerificationAsync(userID)
+		s.recordEvent(r, userID, repository.EventEmailChanged, repository.OutcomeSuccess, "new email: "+input.Email)
+	}
+	if usernameChanged {
+		s.recordEvent(r, userID, repository.EventUsernameChanged, repository.OutcomeSuccess, "new username: "+input.Username)
+	}
+
 	s.Logger.Info.Printf("User updated: %d", userID)
 	httputil.WriteSuccessMessage(*s.Logger, w, "User updated successfully", http.StatusOK)
 }
 
-func (s *Server) handleDeleteUser(w http.ResponseWriter, r *http.Request) {
-	s.Logger.Info.Println("Deleting user account")
-
-	userID, err := s.getUserIDFromToken(r)
-	if err != nil {
-		httputil.HandleError(*s.Logger, w, err.Error(), nil, http.StatusUnauthorized)
-		return
-	}
-
-	err = s.DBOperations.DeleteUser(r.Context(), userID)
+// Helper function to extract userID from Authorization header
+func (s *Server) getUserIDFromToken(r *http.Request) (int, error) {
+	claims, err := s.getClaimsFromToken(r)
 	if err != nil {
-		httputil.HandleError(*s.Logger, w, "Failed to delete user", err, http.StatusInternalServerError)
-		return
+		return 0, err
 	}
 
-	s.Logger.Info.Printf("User deleted: %d", userID)
-	httputil.WriteSuccessMessage(*s.Logger, w, "User deleted successfully", http.StatusOK)
+	return claims.UserID, nil
 }
 
-// Helper function to extract userID from Authorization header
-func (s *Server) getUserIDFromToken(r *http.Request) (int, error) {
+// Helper function to validate the token from Authorization header against the revocation store
+func (s *Server) getClaimsFromToken(r *http.Request) (*token.UserClaims, error) {
 	authHeader := r.Header.Get("Authorization")
 	if authHeader == "" {
-		return 0, errors.New("missing Authorization header")
+		return nil, errors.New("missing Authorization header")
 	}
 
 	tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-	claims, err := token.ValidateJWT(tokenString, s.Config.Salt)
+	claims, err := token.ValidateJWT(tokenString, s.Keys)
 	if err != nil {
-		return 0, errors.New("invalid or expired token")
+		return nil, errors.New("invalid or expired token")
 	}
 
-	return claims.UserID, nil
+	active, err := s.DBOperations.IsAccessTokenActive(r.Context(), claims.ID)
+	if err != nil {
+		s.Logger.Error.Printf("Failed to check token revocation: %v", err)
+		return nil, errors.New("invalid or expired token")
+	}
+	if !active {
+		return nil, errors.New("token has been revoked")
+	}
+
+	return claims, nil
 }
@@ -0,0 +1,167 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ciameksw/mood-api/auth/internal/auth/repository"
+	"github.com/ciameksw/mood-api/auth/internal/auth/token"
+	"github.com/ciameksw/mood-api/pkg/httputil"
+)
+
+func (s *Server) handleAdminGetUsers(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Listing users")
+
+	if _, ok := s.authorizeAdmin(w, r); !ok {
+		return
+	}
+
+	users, err := s.DBOperations.GetUsersWithRoles(r.Context())
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to retrieve users", err, http.StatusInternalServerError)
+		return
+	}
+
+	httputil.WriteData(*s.Logger, w, users, http.StatusOK)
+}
+
+type setRolesInput struct {
+	Roles []string `json:"roles" validate:"required,dive,required"`
+}
+
+func (s *Server) handleAdminSetUserRoles(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Setting user roles")
+
+	claims, ok := s.authorizeAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	userID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Invalid id parameter", err, http.StatusBadRequest)
+		return
+	}
+
+	var input setRolesInput
+	err = json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Invalid request payload", err, http.StatusBadRequest)
+		return
+	}
+
+	err = s.Validator.Struct(input)
+	if err != nil {
+		httputil.HandleValidationError(*s.Logger, w, err)
+		return
+	}
+
+	for _, role := range input.Roles {
+		if !token.IsValidRole(role) {
+			httputil.HandleError(*s.Logger, w, "Unknown role: "+role, nil, http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Keeps the last admin from locking everyone out of the admin endpoints
+	if userID == claims.UserID && !slices.Contains(input.Roles, token.RoleAdmin) {
+		httputil.HandleError(*s.Logger, w, "You cannot remove your own admin role", nil, http.StatusBadRequest)
+		return
+	}
+
+	user, err := s.DBOperations.GetUserByID(r.Context(), userID)
+	if err != nil {
+		if err.Error() == "user not found" {
+			httputil.HandleError(*s.Logger, w, "User not found", nil, http.StatusNotFound)
+			return
+		}
+		httputil.HandleError(*s.Logger, w, "Failed to retrieve user", err, http.StatusInternalServerError)
+		return
+	}
+
+	current, err := s.DBOperations.GetUserRoles(r.Context(), user.ID)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to retrieve user roles", err, http.StatusInternalServerError)
+		return
+	}
+
+	err = s.DBOperations.SetUserRoles(r.Context(), user.ID, input.Roles)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to update user roles", err, http.StatusInternalServerError)
+		return
+	}
+
+	// Roles live in the access tokens, so a removed role only stops working once the user's tokens are revoked
+	for _, role := range current {
+		if !slices.Contains(input.Roles, role) {
+			if err := s.DBOperations.RevokeUserTokens(r.Context(), user.ID); err != nil {
+				httputil.HandleError(*s.Logger, w, "Failed to revoke user tokens", err, http.StatusInternalServerError)
+				return
+			}
+			break
+		}
+	}
+
+	s.recordEvent(r, user.ID, repository.EventRolesChanged, repository.OutcomeSuccess, fmt.Sprintf("set to %v by user %d", input.Roles, claims.UserID))
+	s.Logger.Info.Printf("User %d set roles of user %d to %v", claims.UserID, user.ID, input.Roles)
+	httputil.WriteSuccessMessage(*s.Logger, w, "User roles updated successfully", http.StatusOK)
+}
+
+// Helper function to check that the request carries an access token with the admin role.
+// It writes the error response itself and reports whether the request may continue.
+func (s *Server) authorizeAdmin(w http.ResponseWriter, r *http.Request) (*token.UserClaims, bool) {
+	claims, err := s.getClaimsFromToken(r)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, err.Error(), nil, http.StatusUnauthorized)
+		return nil, false
+	}
+
+	if !claims.HasRole(token.RoleAdmin) {
+		httputil.HandleError(*s.Logger, w, "Forbidden: admin role required", nil, http.StatusForbidden)
+		return nil, false
+	}
+
+	return claims, true
+}
+
+// BootstrapAdmin grants the admin role to the account configured in ADMIN_BOOTSTRAP_EMAIL if it already exists
+func (s *Server) BootstrapAdmin(ctx context.Context) {
+	if s.Config.AdminBootstrapEmail == "" {
+		return
+	}
+
+	user, err := s.DBOperations.GetUserByEmail(ctx, s.Config.AdminBootstrapEmail)
+	if err != nil {
+		if err.Error() != "user not found" {
+			s.Logger.Error.Printf("Failed to look up bootstrap admin: %v", err)
+		}
+		return
+	}
+
+	s.grantBootstrapAdmin(ctx, user.ID, user.Email, user.VerifiedAt != nil)
+}
+
+// Helper function to grant the admin role to the bootstrap account. The address has to be verified first,
+// otherwise whoever registers the configured email before its owner would become admin.
+func (s *Server) grantBootstrapAdmin(ctx context.Context, userID int, email string, verified bool) {
+	if s.Config.AdminBootstrapEmail == "" || !strings.EqualFold(email, s.Config.AdminBootstrapEmail) {
+		return
+	}
+
+	if !verified {
+		s.Logger.Info.Printf("Bootstrap admin %d has not verified the email address yet, admin role not granted", userID)
+		return
+	}
+
+	if err := s.DBOperations.AddUserRole(ctx, userID, token.RoleAdmin); err != nil {
+		s.Logger.Error.Printf("Failed to grant admin role to user %d: %v", userID, err)
+		return
+	}
+
+	s.Logger.Info.Printf("Admin role granted to bootstrap user %d", userID)
+}
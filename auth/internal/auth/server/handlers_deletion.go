@@ -0,0 +1,198 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/ciameksw/mood-api/auth/internal/auth/mailer"
+	"github.com/ciameksw/mood-api/auth/internal/auth/repository"
+	"github.com/ciameksw/mood-api/auth/internal/auth/token"
+	"github.com/ciameksw/mood-api/pkg/httputil"
+)
+
+// Accounts purged per run of the purge job, the rest wait for the next run
+const purgeBatchSize = 100
+
+type deleteUserResponse struct {
+	Message    string    `json:"message"`
+	PurgeAfter time.Time `json:"purgeAfter"`
+}
+
+func (s *Server) handleDeleteUser(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Deleting user account")
+
+	userID, err := s.getUserIDFromToken(r)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, err.Error(), nil, http.StatusUnauthorized)
+		return
+	}
+
+	restoreToken, restoreHash, err := token.GenerateRandomToken()
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to generate restore token", err, http.StatusInternalServerError)
+		return
+	}
+
+	purgeAfter := time.Now().Add(s.Config.AccountDeletionGracePeriod)
+	err = s.DBOperations.ScheduleUserDeletion(r.Context(), userID, restoreHash, purgeAfter)
+	if err != nil {
+		if err.Error() == "user not found" {
+			httputil.HandleError(*s.Logger, w, "User not found", nil, http.StatusNotFound)
+			return
+		}
+		httputil.HandleError(*s.Logger, w, "Failed to delete user", err, http.StatusInternalServerError)
+		return
+	}
+
+	err = s.DBOperations.RevokeUserTokens(r.Context(), userID)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to revoke tokens", err, http.StatusInternalServerError)
+		return
+	}
+
+	s.sendRestoreLinkAsync(userID, restoreToken, purgeAfter)
+
+	s.recordEvent(r, userID, repository.EventDeletionRequested, repository.OutcomeSuccess, "purge after "+purgeAfter.UTC().Format(time.RFC3339))
+	s.Logger.Info.Printf("User %d scheduled for deletion after %s", userID, purgeAfter.Format(time.RFC3339))
+	resp := deleteUserResponse{
+		Message:    "User scheduled for deletion, it can be restored until it is purged",
+		PurgeAfter: purgeAfter,
+	}
+	httputil.WriteData(*s.Logger, w, resp, http.StatusOK)
+}
+
+type restoreUserInput struct {
+	Token string `json:"token" validate:"required"`
+}
+
+func (s *Server) handleRestoreUser(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Restoring user account")
+	var input restoreUserInput
+
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Invalid request payload", err, http.StatusBadRequest)
+		return
+	}
+
+	err = s.Validator.Struct(input)
+	if err != nil {
+		httputil.HandleValidationError(*s.Logger, w, err)
+		return
+	}
+
+	userID, err := s.DBOperations.GetUserIDByRestoreToken(r.Context(), token.HashRandomToken(input.Token))
+	if err != nil {
+		if err.Error() == "restore token not found" {
+			httputil.HandleError(*s.Logger, w, "Invalid or expired restore token", nil, http.StatusBadRequest)
+			return
+		}
+		httputil.HandleError(*s.Logger, w, "Failed to retrieve restore token", err, http.StatusInternalServerError)
+		return
+	}
+
+	err = s.DBOperations.RestoreUser(r.Context(), userID, userID)
+	if err != nil {
+		if err.Error() == "user not scheduled for deletion" {
+			httputil.HandleError(*s.Logger, w, "Invalid or expired restore token", nil, http.StatusBadRequest)
+			return
+		}
+		httputil.HandleError(*s.Logger, w, "Failed to restore user", err, http.StatusInternalServerError)
+		return
+	}
+
+	s.recordEvent(r, userID, repository.EventAccountRestored, repository.OutcomeSuccess, "")
+	s.Logger.Info.Printf("User %d restored", userID)
+	httputil.WriteSuccessMessage(*s.Logger, w, "User restored successfully, you can log in again", http.StatusOK)
+}
+
+func (s *Server) handleAdminRestoreUser(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Restoring user account as admin")
+
+	claims, ok := s.authorizeAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	userID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Invalid id parameter", err, http.StatusBadRequest)
+		return
+	}
+
+	err = s.DBOperations.RestoreUser(r.Context(), userID, claims.UserID)
+	if err != nil {
+		if err.Error() == "user not scheduled for deletion" {
+			httputil.HandleError(*s.Logger, w, "User not found or not scheduled for deletion", nil, http.StatusNotFound)
+			return
+		}
+		httputil.HandleError(*s.Logger, w, "Failed to restore user", err, http.StatusInternalServerError)
+		return
+	}
+
+	s.recordEvent(r, userID, repository.EventAccountRestored, repository.OutcomeSuccess, fmt.Sprintf("by user %d", claims.UserID))
+	s.Logger.Info.Printf("User %d restored user %d", claims.UserID, userID)
+	httputil.WriteSuccessMessage(*s.Logger, w, "User restored successfully", http.StatusOK)
+}
+
+// Helper function to email the restore link in the background, the deletion stands even if the email fails
+func (s *Server) sendRestoreLinkAsync(userID int, restoreToken string, purgeAfter time.Time) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		user, err := s.DBOperations.GetUserByID(ctx, userID)
+		if err != nil {
+			s.Logger.Error.Printf("Failed to look up user for restore link: %v", err)
+			return
+		}
+
+		link := s.Config.AppBaseURL + "/restore-account?token=" + url.QueryEscape(restoreToken)
+		s.sendMail(ctx, mailer.Message{
+			To:      user.Email,
+			Subject: "Your account is scheduled for deletion",
+			Body: fmt.Sprintf("Hi %s,\n\nYour account and all of its data will be permanently deleted on %s. If you changed your mind, restore it with the link below:\n\n%s",
+				user.Username, purgeAfter.UTC().Format("2 January 2006 15:04 MST"), link),
+		})
+	}()
+}
+
+// Helper function to erase accounts whose grace period has ended. Data in the other services goes first,
+// so the users row, which their tables reference, is only deleted once nothing points to it any more.
+func (s *Server) purgeDeletedAccounts(ctx context.Context) error {
+	userIDs, err := s.DBOperations.GetUsersDueForPurge(ctx, purgeBatchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, userID := range userIDs {
+		if err := s.purgeAccount(ctx, userID); err != nil {
+			s.Logger.Error.Printf("Failed to purge user %d, retrying on the next run: %v", userID, err)
+			if auditErr := s.DBOperations.AddDeletionAudit(ctx, userID, repository.DeletionPurgeFailed, err.Error()); auditErr != nil {
+				s.Logger.Error.Printf("Failed to record purge failure of user %d: %v", userID, auditErr)
+			}
+			continue
+		}
+
+		s.addEvent(ctx, repository.AuthEvent{
+			UserID:  &userID,
+			Type:    repository.EventAccountPurged,
+			Outcome: repository.OutcomeSuccess,
+		})
+		s.Logger.Info.Printf("Purged user %d", userID)
+	}
+
+	return nil
+}
+
+func (s *Server) purgeAccount(ctx context.Context, userID int) error {
+	if err := s.UserData.DeleteUserData(ctx, userID); err != nil {
+		return err
+	}
+	return s.DBOperations.PurgeUser(ctx, userID)
+}
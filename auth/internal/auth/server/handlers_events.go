@@ -0,0 +1,183 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/ciameksw/mood-api/auth/internal/auth/repository"
+	"github.com/ciameksw/mood-api/pkg/httputil"
+)
+
+const (
+	defaultEventsLimit = 50
+	maxEventsLimit     = 200
+)
+
+func (s *Server) handleGetEvents(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Getting user account activity")
+
+	userID, err := s.getUserIDFromToken(r)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, err.Error(), nil, http.StatusUnauthorized)
+		return
+	}
+
+	filter, err := parseEventFilter(r.URL.Query())
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, err.Error(), nil, http.StatusBadRequest)
+		return
+	}
+	// Users only see their own account, whatever the query says
+	filter.UserID = userID
+	filter.IPAddress = ""
+
+	events, err := s.DBOperations.GetAuthEvents(r.Context(), filter)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to retrieve events", err, http.StatusInternalServerError)
+		return
+	}
+
+	httputil.WriteData(*s.Logger, w, events, http.StatusOK)
+}
+
+func (s *Server) handleAdminGetEvents(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Querying auth events")
+
+	if _, ok := s.authorizeAdmin(w, r); !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	filter, err := parseEventFilter(q)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, err.Error(), nil, http.StatusBadRequest)
+		return
+	}
+
+	if v := q.Get("userId"); v != "" {
+		filter.UserID, err = strconv.Atoi(v)
+		if err != nil || filter.UserID < 1 {
+			httputil.HandleError(*s.Logger, w, "Invalid userId parameter", nil, http.StatusBadRequest)
+			return
+		}
+	}
+
+	events, err := s.DBOperations.GetAuthEvents(r.Context(), filter)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to retrieve events", err, http.StatusInternalServerError)
+		return
+	}
+
+	httputil.WriteData(*s.Logger, w, events, http.StatusOK)
+}
+
+// Helper function to read the type, outcome, ip, from, to, limit and offset query parameters
+func parseEventFilter(q url.Values) (repository.AuthEventFilter, error) {
+	filter := repository.AuthEventFilter{
+		Type:      q.Get("type"),
+		Outcome:   q.Get("outcome"),
+		IPAddress: q.Get("ip"),
+		Limit:     defaultEventsLimit,
+	}
+
+	if filter.Outcome != "" && filter.Outcome != repository.OutcomeSuccess && filter.Outcome != repository.OutcomeFailure {
+		return filter, errors.New("outcome must be success or failure")
+	}
+
+	var err error
+	if v := q.Get("from"); v != "" {
+		if filter.From, err = parseEventTime(v, false); err != nil {
+			return filter, errors.New("from must be an RFC 3339 timestamp or a YYYY-MM-DD date")
+		}
+	}
+	if v := q.Get("to"); v != "" {
+		if filter.To, err = parseEventTime(v, true); err != nil {
+			return filter, errors.New("to must be an RFC 3339 timestamp or a YYYY-MM-DD date")
+		}
+	}
+
+	if v := q.Get("limit"); v != "" {
+		filter.Limit, err = strconv.Atoi(v)
+		if err != nil || filter.Limit < 1 || filter.Limit > maxEventsLimit {
+			return filter, errors.New("limit must be between 1 and " + strconv.Itoa(maxEventsLimit))
+		}
+	}
+	if v := q.Get("offset"); v != "" {
+		filter.Offset, err = strconv.Atoi(v)
+		if err != nil || filter.Offset < 0 {
+			return filter, errors.New("offset must be a non-negative number")
+		}
+	}
+
+	return filter, nil
+}
+
+// Helper function to parse a timestamp or a date, a date used as the upper bound includes the whole day
+func parseEventTime(v string, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+
+	t, err := time.Parse("2006-01-02", v)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+// Helper function to record an event caused by a request, userID 0 means no known account.
+// Failures are only logged, the audit log must not break the action it records.
+func (s *Server) recordEvent(r *http.Request, userID int, eventType, outcome, detail string) {
+	s.addEvent(r.Context(), repository.AuthEvent{
+		UserID:    eventUserID(userID),
+		Type:      eventType,
+		Outcome:   outcome,
+		IPAddress: clientIP(r),
+		UserAgent: r.UserAgent(),
+		Detail:    detail,
+	})
+}
+
+func (s *Server) addEvent(ctx context.Context, e repository.AuthEvent) {
+	if err := s.DBOperations.AddAuthEvent(ctx, e); err != nil {
+		s.Logger.Error.Printf("Failed to record %s event: %v", e.Type, err)
+	}
+}
+
+func eventUserID(userID int) *int {
+	if userID == 0 {
+		return nil
+	}
+	return &userID
+}
+
+func (s *Server) cleanupAuthEvents(ctx context.Context) error {
+	if s.Config.AuthEventRetention <= 0 {
+		return nil
+	}
+
+	deleted, err := s.DBOperations.DeleteAuthEventsBefore(ctx, time.Now().Add(-s.Config.AuthEventRetention))
+	if err != nil {
+		return err
+	}
+
+	if deleted > 0 {
+		s.Logger.Info.Printf("Deleted %d auth events past retention", deleted)
+	}
+	return nil
+}
+
+// Helper function to append a note to an event detail
+func joinDetail(detail, note string) string {
+	if detail == "" {
+		return note
+	}
+	return detail + ", " + note
+}
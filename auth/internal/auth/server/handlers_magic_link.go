@@ -0,0 +1,211 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ciameksw/mood-api/auth/internal/auth/mailer"
+	"github.com/ciameksw/mood-api/auth/internal/auth/repository"
+	"github.com/ciameksw/mood-api/auth/internal/auth/token"
+	"github.com/ciameksw/mood-api/pkg/httputil"
+)
+
+// magicLinkDeviceCookie binds a login link to the browser that requested it
+const magicLinkDeviceCookie = "magic_link_device"
+
+type magicLinkInput struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type magicLinkResponse struct {
+	Message string `json:"message"`
+	// DeviceToken has to be sent along with the link token, apps keep it until the link is opened
+	DeviceToken string `json:"deviceToken"`
+}
+
+func (s *Server) handleRequestMagicLink(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Requesting login link")
+	var input magicLinkInput
+
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Invalid request payload", err, http.StatusBadRequest)
+		return
+	}
+
+	err = s.Validator.Struct(input)
+	if err != nil {
+		httputil.HandleValidationError(*s.Logger, w, err)
+		return
+	}
+
+	// Requests are counted for every address, registered or not, so the limit does not reveal accounts
+	key := accountKey(input.Email)
+	if retryAfter := s.lockedFor(r.Context(), s.MagicLinkLimiter, key); retryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		httputil.HandleError(*s.Logger, w, "Too many login links requested, try again later", nil, http.StatusTooManyRequests)
+		return
+	}
+	if _, err := s.MagicLinkLimiter.Fail(r.Context(), key); err != nil {
+		s.Logger.Error.Printf("Failed to count login link request: %v", err)
+	}
+
+	deviceToken, deviceHash, err := token.GenerateRandomToken()
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to generate device token", err, http.StatusInternalServerError)
+		return
+	}
+
+	// The work happens in the background so neither the response nor its timing reveals whether the email is registered
+	go s.requestMagicLink(input.Email, deviceHash, clientIP(r), r.UserAgent())
+
+	http.SetCookie(w, s.magicLinkDeviceCookie(deviceToken, int(s.Config.MagicLinkTTL.Seconds())))
+	resp := magicLinkResponse{
+		Message:     "If an account with that email exists, a login link has been sent",
+		DeviceToken: deviceToken,
+	}
+	httputil.WriteData(*s.Logger, w, resp, http.StatusOK)
+}
+
+// Helper function to create a login link and email it when the address belongs to a user
+func (s *Server) requestMagicLink(email, deviceHash, ip, userAgent string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	user, err := s.DBOperations.GetUserByEmail(ctx, email)
+	if err != nil {
+		if err.Error() != "user not found" {
+			s.Logger.Error.Printf("Failed to look up user for login link: %v", err)
+		}
+		return
+	}
+
+	// Deleted accounts are restored with the link from the deletion email instead
+	if user.DeletedAt != nil {
+		return
+	}
+
+	linkToken, linkHash, err := token.GenerateRandomToken()
+	if err != nil {
+		s.Logger.Error.Printf("Failed to generate login link token: %v", err)
+		return
+	}
+
+	err = s.DBOperations.CreateMagicLinkToken(ctx, user.ID, user.Email, linkHash, deviceHash, time.Now().Add(s.Config.MagicLinkTTL))
+	if err != nil {
+		s.Logger.Error.Printf("Failed to store login link token: %v", err)
+		return
+	}
+
+	link := s.Config.AppBaseURL + "/magic-link?token=" + url.QueryEscape(linkToken)
+	s.sendMail(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Your login link",
+		Body: fmt.Sprintf("Hi %s,\n\nUse the link below to log in:\n\n%s\n\nThe link works once, expires in %s and has to be opened on the device where you requested it. If you did not request it, you can ignore this email.",
+			user.Username, link, s.Config.MagicLinkTTL),
+	})
+
+	s.addEvent(ctx, repository.AuthEvent{
+		UserID:    &user.ID,
+		Type:      repository.EventMagicLinkRequested,
+		Outcome:   repository.OutcomeSuccess,
+		IPAddress: ip,
+		UserAgent: userAgent,
+	})
+	s.Logger.Info.Printf("Login link requested for user %d", user.ID)
+}
+
+type consumeMagicLinkInput struct {
+	Token string `json:"token" validate:"required"`
+	// Browsers may leave it out, the cookie set with the link request is used instead
+	DeviceToken string `json:"deviceToken"`
+}
+
+func (s *Server) handleConsumeMagicLink(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Logging in with login link")
+	var input consumeMagicLinkInput
+
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Invalid request payload", err, http.StatusBadRequest)
+		return
+	}
+
+	err = s.Validator.Struct(input)
+	if err != nil {
+		httputil.HandleValidationError(*s.Logger, w, err)
+		return
+	}
+
+	mlt, err := s.DBOperations.GetMagicLinkToken(r.Context(), token.HashRandomToken(input.Token))
+	if err != nil {
+		if err.Error() == "magic link not found" {
+			httputil.HandleError(*s.Logger, w, "Invalid or expired login link", nil, http.StatusBadRequest)
+			return
+		}
+		httputil.HandleError(*s.Logger, w, "Failed to retrieve login link", err, http.StatusInternalServerError)
+		return
+	}
+
+	if mlt.UsedAt != nil || time.Now().After(mlt.ExpiresAt) {
+		httputil.HandleError(*s.Logger, w, "Invalid or expired login link", nil, http.StatusBadRequest)
+		return
+	}
+
+	// A link opened elsewhere is left unused so the requesting device can still open it
+	if s.Config.MagicLinkBindDevice {
+		deviceToken := input.DeviceToken
+		if deviceToken == "" {
+			if cookie, err := r.Cookie(magicLinkDeviceCookie); err == nil {
+				deviceToken = cookie.Value
+			}
+		}
+		if subtle.ConstantTimeCompare([]byte(token.HashRandomToken(deviceToken)), []byte(mlt.DeviceHash)) != 1 {
+			s.recordEvent(r, mlt.UserID, repository.EventMagicLinkLogin, repository.OutcomeFailure, "opened on another device")
+			httputil.HandleError(*s.Logger, w, "The login link has to be opened on the device where it was requested", nil, http.StatusBadRequest)
+			return
+		}
+	}
+
+	err = s.DBOperations.UseMagicLinkToken(r.Context(), mlt)
+	if err != nil {
+		if err.Error() == "magic link already used" || err.Error() == "email changed" {
+			httputil.HandleError(*s.Logger, w, "Invalid or expired login link", nil, http.StatusBadRequest)
+			return
+		}
+		httputil.HandleError(*s.Logger, w, "Failed to use login link", err, http.StatusInternalServerError)
+		return
+	}
+	http.SetCookie(w, s.magicLinkDeviceCookie("", -1))
+
+	user, err := s.DBOperations.GetUserByID(r.Context(), mlt.UserID)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to retrieve user", err, http.StatusInternalServerError)
+		return
+	}
+
+	// Like a password reset, proving control of the mailbox lifts a lockout caused by failed logins
+	if s.completeLogin(w, r, user, repository.EventMagicLinkLogin, "") {
+		s.resetAccountLockout(r.Context(), user.Email)
+	}
+}
+
+func (s *Server) magicLinkDeviceCookie(value string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     magicLinkDeviceCookie,
+		Value:    value,
+		Path:     "/auth/magic-link",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   strings.HasPrefix(s.Config.AppBaseURL, "https://"),
+		SameSite: http.SameSiteLaxMode,
+	}
+}
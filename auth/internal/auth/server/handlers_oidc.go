@@ -0,0 +1,271 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ciameksw/mood-api/auth/internal/auth/oidc"
+	"github.com/ciameksw/mood-api/auth/internal/auth/repository"
+	"github.com/ciameksw/mood-api/auth/internal/auth/token"
+	"github.com/ciameksw/mood-api/pkg/httputil"
+)
+
+// oidcStateCookie binds a pending provider login to the browser that started it
+const oidcStateCookie = "oidc_state"
+
+var usernameInvalidChars = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+type oidcProviderResponse struct {
+	Name     string `json:"name"`
+	LoginURL string `json:"loginUrl"`
+}
+
+func (s *Server) handleGetOIDCProviders(w http.ResponseWriter, r *http.Request) {
+	names := make([]string, 0, len(s.OIDCProviders))
+	for name := range s.OIDCProviders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	resp := make([]oidcProviderResponse, 0, len(names))
+	for _, name := range names {
+		resp = append(resp, oidcProviderResponse{
+			Name:     name,
+			LoginURL: "/auth/oidc/" + name + "/login",
+		})
+	}
+	httputil.WriteData(*s.Logger, w, resp, http.StatusOK)
+}
+
+func (s *Server) handleOIDCLogin(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Starting identity provider login")
+
+	provider, ok := s.OIDCProviders[r.PathValue("provider")]
+	if !ok {
+		httputil.HandleError(*s.Logger, w, "Unknown identity provider", nil, http.StatusNotFound)
+		return
+	}
+
+	state, stateHash, err := token.GenerateRandomToken()
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to generate login state", err, http.StatusInternalServerError)
+		return
+	}
+	nonce, _, err := token.GenerateRandomToken()
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to generate login state", err, http.StatusInternalServerError)
+		return
+	}
+	codeVerifier, _, err := token.GenerateRandomToken()
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to generate login state", err, http.StatusInternalServerError)
+		return
+	}
+
+	authURL, err := provider.AuthCodeURL(r.Context(), state, nonce, codeVerifier)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Identity provider is unavailable", err, http.StatusBadGateway)
+		return
+	}
+
+	err = s.DBOperations.CreateOIDCLoginState(r.Context(), stateHash, provider.Name, nonce, codeVerifier, time.Now().Add(s.Config.OIDCLoginTTL))
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to store login state", err, http.StatusInternalServerError)
+		return
+	}
+
+	http.SetCookie(w, s.oidcStateCookie(provider, state, int(s.Config.OIDCLoginTTL.Seconds())))
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+func (s *Server) handleOIDCCallback(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Completing identity provider login")
+
+	provider, ok := s.OIDCProviders[r.PathValue("provider")]
+	if !ok {
+		httputil.HandleError(*s.Logger, w, "Unknown identity provider", nil, http.StatusNotFound)
+		return
+	}
+
+	q := r.URL.Query()
+	if providerErr := q.Get("error"); providerErr != "" {
+		httputil.HandleError(*s.Logger, w, "Identity provider returned an error: "+providerErr, nil, http.StatusBadRequest)
+		return
+	}
+
+	code := q.Get("code")
+	state := q.Get("state")
+	if code == "" || state == "" {
+		httputil.HandleError(*s.Logger, w, "Missing code or state parameter", nil, http.StatusBadRequest)
+		return
+	}
+
+	// A state arriving without the cookie set at login was started in another browser
+	cookie, err := r.Cookie(oidcStateCookie)
+	if err != nil || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(state)) != 1 {
+		httputil.HandleError(*s.Logger, w, "Invalid or expired login state", nil, http.StatusBadRequest)
+		return
+	}
+	http.SetCookie(w, s.oidcStateCookie(provider, "", -1))
+
+	loginState, err := s.DBOperations.ConsumeOIDCLoginState(r.Context(), token.HashRandomToken(state))
+	if err != nil {
+		if err.Error() == "login state not found" {
+			httputil.HandleError(*s.Logger, w, "Invalid or expired login state", nil, http.StatusBadRequest)
+			return
+		}
+		httputil.HandleError(*s.Logger, w, "Failed to retrieve login state", err, http.StatusInternalServerError)
+		return
+	}
+
+	if loginState.Provider != provider.Name || time.Now().After(loginState.ExpiresAt) {
+		httputil.HandleError(*s.Logger, w, "Invalid or expired login state", nil, http.StatusBadRequest)
+		return
+	}
+
+	claims, err := provider.Exchange(r.Context(), code, loginState.CodeVerifier, loginState.Nonce)
+	if err != nil {
+		s.recordEvent(r, 0, repository.EventOIDCLogin, repository.OutcomeFailure, "provider: "+provider.Name)
+		httputil.HandleError(*s.Logger, w, "Failed to sign in with identity provider", err, http.StatusUnauthorized)
+		return
+	}
+
+	user, ok := s.findOrCreateOIDCUser(w, r, provider.Name, claims)
+	if !ok {
+		return
+	}
+
+	s.completeLogin(w, r, user, repository.EventOIDCLogin, "provider: "+provider.Name)
+}
+
+// Helper function to resolve the local account of a provider identity. Known identities sign in to their account,
+// a verified email of an existing account links the identity to it and anything else creates a new account.
+// It writes the error response itself and reports whether the login may continue.
+func (s *Server) findOrCreateOIDCUser(w http.ResponseWriter, r *http.Request, providerName string, claims *oidc.Claims) (*repository.User, bool) {
+	ctx := r.Context()
+
+	userID, err := s.DBOperations.GetUserIDByIdentity(ctx, providerName, claims.Subject)
+	if err == nil {
+		return s.getOIDCUser(w, r, userID)
+	}
+	if err.Error() != "identity not found" {
+		httputil.HandleError(*s.Logger, w, "Failed to retrieve identity", err, http.StatusInternalServerError)
+		return nil, false
+	}
+
+	if claims.Email == "" || !claims.EmailVerified {
+		httputil.HandleError(*s.Logger, w, "The identity provider did not confirm an email address", nil, http.StatusForbidden)
+		return nil, false
+	}
+
+	existing, err := s.DBOperations.GetUserByEmail(ctx, claims.Email)
+	if err != nil && err.Error() != "user not found" {
+		httputil.HandleError(*s.Logger, w, "Failed to check existing user", err, http.StatusInternalServerError)
+		return nil, false
+	}
+
+	if existing != nil {
+		// Otherwise whoever registered the address without owning it would keep a password to the linked account
+		if existing.VerifiedAt == nil {
+			httputil.HandleError(*s.Logger, w, "An account with this email already exists, verify its email address before signing in with "+providerName, nil, http.StatusConflict)
+			return nil, false
+		}
+
+		err = s.DBOperations.LinkUserIdentity(ctx, existing.ID, providerName, claims.Subject, claims.Email)
+		if err != nil {
+			if err.Error() == "identity already linked" {
+				httputil.HandleError(*s.Logger, w, "This account is already linked to another "+providerName+" identity", nil, http.StatusConflict)
+				return nil, false
+			}
+			httputil.HandleError(*s.Logger, w, "Failed to link identity", err, http.StatusInternalServerError)
+			return nil, false
+		}
+
+		s.recordEvent(r, existing.ID, repository.EventOIDCLinked, repository.OutcomeSuccess, "provider: "+providerName)
+		s.Logger.Info.Printf("Linked %s identity to user %d", providerName, existing.ID)
+		return existing, true
+	}
+
+	username, err := s.generateUsername(ctx, claims)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to generate username", err, http.StatusInternalServerError)
+		return nil, false
+	}
+
+	userID, err = s.DBOperations.CreateUserWithIdentity(ctx, username, claims.Email, providerName, claims.Subject)
+	if err != nil {
+		// Lost a race against a concurrent registration or sign-in, a retry resolves it
+		if err.Error() == "user already exists" || err.Error() == "identity already linked" {
+			httputil.HandleError(*s.Logger, w, "Account could not be created, please try again", err, http.StatusConflict)
+			return nil, false
+		}
+		httputil.HandleError(*s.Logger, w, "Failed to create user", err, http.StatusInternalServerError)
+		return nil, false
+	}
+
+	s.recordEvent(r, userID, repository.EventRegister, repository.OutcomeSuccess, "provider: "+providerName)
+	s.Logger.Info.Printf("User registered through %s id: %d, username: %s", providerName, userID, username)
+	s.grantBootstrapAdmin(ctx, userID, claims.Email, true)
+	return s.getOIDCUser(w, r, userID)
+}
+
+func (s *Server) getOIDCUser(w http.ResponseWriter, r *http.Request, userID int) (*repository.User, bool) {
+	user, err := s.DBOperations.GetUserByID(r.Context(), userID)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to retrieve user", err, http.StatusInternalServerError)
+		return nil, false
+	}
+	return user, true
+}
+
+// Helper function to derive a free username from the provider's preferred username or the email address
+func (s *Server) generateUsername(ctx context.Context, claims *oidc.Claims) (string, error) {
+	base := claims.PreferredUsername
+	if base == "" || strings.Contains(base, "@") {
+		base, _, _ = strings.Cut(claims.Email, "@")
+	}
+	base = usernameInvalidChars.ReplaceAllString(base, "")
+	if len(base) < 3 {
+		base = "user"
+	}
+	if len(base) > 23 {
+		base = base[:23]
+	}
+
+	username := base
+	for range 5 {
+		exists, err := s.DBOperations.UserExistsByUsername(ctx, username)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return username, nil
+		}
+
+		suffix, err := token.GenerateID()
+		if err != nil {
+			return "", err
+		}
+		username = base + "_" + suffix[:6]
+	}
+
+	return username, nil
+}
+
+func (s *Server) oidcStateCookie(provider *oidc.Provider, value string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    value,
+		Path:     "/auth/oidc/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   strings.HasPrefix(provider.RedirectURL, "https://"),
+		// Lax still sends the cookie on the top-level redirect back from the provider
+		SameSite: http.SameSiteLaxMode,
+	}
+}
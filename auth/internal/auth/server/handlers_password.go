@@ -0,0 +1,301 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/ciameksw/mood-api/auth/internal/auth/mailer"
+	"github.com/ciameksw/mood-api/auth/internal/auth/repository"
+	"github.com/ciameksw/mood-api/auth/internal/auth/token"
+	"github.com/ciameksw/mood-api/pkg/httputil"
+)
+
+type forgotPasswordInput struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+func (s *Server) handleForgotPassword(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Requesting password reset")
+	var input forgotPasswordInput
+
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Invalid request payload", err, http.StatusBadRequest)
+		return
+	}
+
+	err = s.Validator.Struct(input)
+	if err != nil {
+		httputil.HandleValidationError(*s.Logger, w, err)
+		return
+	}
+
+	// The work happens in the background so neither the response nor its timing reveals whether the email is registered
+	go s.requestPasswordReset(input.Email, clientIP(r), r.UserAgent())
+
+	httputil.WriteSuccessMessage(*s.Logger, w, "If an account with that email exists, a password reset link has been sent", http.StatusOK)
+}
+
+// Helper function to create a reset token and email it when the address belongs to a user
+func (s *Server) requestPasswordReset(email, ip, userAgent string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	user, err := s.DBOperations.GetUserByEmail(ctx, email)
+	if err != nil {
+		if err.Error() != "user not found" {
+			s.Logger.Error.Printf("Failed to look up user for password reset: %v", err)
+		}
+		return
+	}
+
+	// Deleted accounts are restored with the link from the deletion email instead
+	if user.DeletedAt != nil {
+		return
+	}
+
+	resetToken, resetHash, err := token.GenerateRandomToken()
+	if err != nil {
+		s.Logger.Error.Printf("Failed to generate reset token: %v", err)
+		return
+	}
+
+	err = s.DBOperations.CreatePasswordResetToken(ctx, user.ID, resetHash, time.Now().Add(s.Config.PasswordResetTTL))
+	if err != nil {
+		s.Logger.Error.Printf("Failed to store reset token: %v", err)
+		return
+	}
+
+	link := s.Config.AppBaseURL + "/reset-password?token=" + url.QueryEscape(resetToken)
+	s.sendMail(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nSomeone requested a password reset for your account. Use the link below to choose a new password:\n\n%s\n\nThe link expires in %s. If you did not request a reset, you can ignore this email.",
+			user.Username, link, s.Config.PasswordResetTTL),
+	})
+
+	s.addEvent(ctx, repository.AuthEvent{
+		UserID:    &user.ID,
+		Type:      repository.EventPasswordResetRequested,
+		Outcome:   repository.OutcomeSuccess,
+		IPAddress: ip,
+		UserAgent: userAgent,
+	})
+	s.Logger.Info.Printf("Password reset requested for user %d", user.ID)
+}
+
+type resetPasswordInput struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
+
+func (s *Server) handleResetPassword(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Resetting password")
+	var input resetPasswordInput
+
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Invalid request payload", err, http.StatusBadRequest)
+		return
+	}
+
+	err = s.Validator.Struct(input)
+	if err != nil {
+		httputil.HandleValidationError(*s.Logger, w, err)
+		return
+	}
+
+	prt, err := s.DBOperations.GetPasswordResetToken(r.Context(), token.HashRandomToken(input.Token))
+	if err != nil {
+		if err.Error() == "reset token not found" {
+			httputil.HandleError(*s.Logger, w, "Invalid or expired reset token", nil, http.StatusBadRequest)
+			return
+		}
+		httputil.HandleError(*s.Logger, w, "Failed to retrieve reset token", err, http.StatusInternalServerError)
+		return
+	}
+
+	if prt.UsedAt != nil || time.Now().After(prt.ExpiresAt) {
+		httputil.HandleError(*s.Logger, w, "Invalid or expired reset token", nil, http.StatusBadRequest)
+		return
+	}
+
+	user, err := s.DBOperations.GetUserByID(r.Context(), prt.UserID)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to retrieve user", err, http.StatusInternalServerError)
+		return
+	}
+
+	if !s.checkPasswordPolicy(w, input.Password, user.Username, user.Email) {
+		return
+	}
+
+	hashedPassword, err := s.Passwords.Hash(input.Password)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to hash password", err, http.StatusInternalServerError)
+		return
+	}
+
+	err = s.DBOperations.ResetPassword(r.Context(), prt, hashedPassword)
+	if err != nil {
+		if err.Error() == "reset token already used" {
+			httputil.HandleError(*s.Logger, w, "Invalid or expired reset token", nil, http.StatusBadRequest)
+			return
+		}
+		httputil.HandleError(*s.Logger, w, "Failed to reset password", err, http.StatusInternalServerError)
+		return
+	}
+
+	// Whoever knew the old password must not keep a session
+	err = s.DBOperations.RevokeUserTokens(r.Context(), prt.UserID)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to revoke tokens", err, http.StatusInternalServerError)
+		return
+	}
+
+	// Proving control of the mailbox lifts a lockout caused by someone guessing the old password
+	s.resetAccountLockout(r.Context(), user.Email)
+
+	s.recordEvent(r, prt.UserID, repository.EventPasswordReset, repository.OutcomeSuccess, "")
+	s.Logger.Info.Printf("Password reset for user %d", prt.UserID)
+	httputil.WriteSuccessMessage(*s.Logger, w, "Password reset successfully", http.StatusOK)
+}
+
+type changePasswordInput struct {
+	CurrentPassword string `json:"currentPassword" validate:"required"`
+	NewPassword     string `json:"newPassword" validate:"required"`
+}
+
+func (s *Server) handleChangePassword(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Changing password")
+
+	claims, err := s.getClaimsFromToken(r)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, err.Error(), nil, http.StatusUnauthorized)
+		return
+	}
+
+	var input changePasswordInput
+	err = json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Invalid request payload", err, http.StatusBadRequest)
+		return
+	}
+
+	err = s.Validator.Struct(input)
+	if err != nil {
+		httputil.HandleValidationError(*s.Logger, w, err)
+		return
+	}
+
+	user, err := s.DBOperations.GetUserByID(r.Context(), claims.UserID)
+	if err != nil {
+		if err.Error() == "user not found" {
+			httputil.HandleError(*s.Logger, w, "User not found", nil, http.StatusNotFound)
+			return
+		}
+		httputil.HandleError(*s.Logger, w, "Failed to retrieve user", err, http.StatusInternalServerError)
+		return
+	}
+
+	if !s.checkCurrentPassword(w, r, user, input.CurrentPassword, repository.EventPasswordChanged) {
+		return
+	}
+
+	if !s.checkPasswordPolicy(w, input.NewPassword, user.Username, user.Email) {
+		return
+	}
+
+	hashedPassword, err := s.Passwords.Hash(input.NewPassword)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to hash password", err, http.StatusInternalServerError)
+		return
+	}
+
+	err = s.DBOperations.UpdatePassword(r.Context(), user.ID, hashedPassword)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to update password", err, http.StatusInternalServerError)
+		return
+	}
+
+	// Every other session was opened with the old password, the one making the change stays logged in
+	err = s.DBOperations.RevokeOtherUserTokens(r.Context(), user.ID, claims.SessionID)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to revoke tokens", err, http.StatusInternalServerError)
+		return
+	}
+
+	s.recordEvent(r, user.ID, repository.EventPasswordChanged, repository.OutcomeSuccess, "")
+	s.Logger.Info.Printf("Password changed for user %d", user.ID)
+	httputil.WriteSuccessMessage(*s.Logger, w, "Password changed successfully, other sessions have been logged out", http.StatusOK)
+}
+
+// Helper function to re-authenticate a logged in user before a sensitive change. Wrong passwords count towards
+// the login lockout so a stolen token cannot be used to guess the password. A failure is recorded as eventType.
+// It writes the error response itself and reports whether the handler may continue.
+func (s *Server) checkCurrentPassword(w http.ResponseWriter, r *http.Request, user *repository.User, password, eventType string) bool {
+	// Accounts created through an identity provider have no password until one is set with a password reset
+	if user.PasswordHash == "" {
+		httputil.HandleError(*s.Logger, w, "Account has no password, set one with a password reset first", nil, http.StatusBadRequest)
+		return false
+	}
+
+	ip := clientIP(r)
+	if !s.checkLoginLockout(r.Context(), w, user.Email, ip) {
+		s.recordEvent(r, user.ID, repository.EventLoginLocked, repository.OutcomeFailure, "re-authentication")
+		return false
+	}
+
+	match, _ := s.Passwords.Verify(password, user.PasswordHash)
+	if !match {
+		s.recordLoginFailure(r.Context(), user.Email, ip)
+		s.recordEvent(r, user.ID, eventType, repository.OutcomeFailure, "invalid current password")
+		httputil.HandleError(*s.Logger, w, "Current password is incorrect", nil, http.StatusForbidden)
+		return false
+	}
+
+	return true
+}
+
+// Helper function to check a new password against the password policy.
+// It writes the validation errors itself and reports whether the password is accepted.
+func (s *Server) checkPasswordPolicy(w http.ResponseWriter, password, username, email string) bool {
+	problems := s.Policy.Check(password, username, email)
+	if len(problems) == 0 {
+		return true
+	}
+
+	fields := make([]httputil.FieldError, 0, len(problems))
+	for _, problem := range problems {
+		fields = append(fields, httputil.FieldError{Field: "password", Message: problem})
+	}
+	httputil.WriteValidationErrors(*s.Logger, w, fields)
+	return false
+}
+
+// Helper function to upgrade a password hash made with outdated settings, failures only cost another attempt at the next login
+func (s *Server) rehashPassword(ctx context.Context, user *repository.User, password string) {
+	hash, err := s.Passwords.Hash(password)
+	if err != nil {
+		s.Logger.Error.Printf("Failed to rehash password of user %d: %v", user.ID, err)
+		return
+	}
+
+	if err := s.DBOperations.ReplacePasswordHash(ctx, user.ID, user.PasswordHash, hash); err != nil {
+		s.Logger.Error.Printf("Failed to store rehashed password of user %d: %v", user.ID, err)
+		return
+	}
+
+	s.Logger.Info.Printf("Password hash of user %d upgraded", user.ID)
+}
+
+// Helper function to send an email, logging delivery failures
+func (s *Server) sendMail(ctx context.Context, msg mailer.Message) {
+	if err := s.Mailer.Send(ctx, msg); err != nil {
+		s.Logger.Error.Printf("Failed to send email %q: %v", msg.Subject, err)
+	}
+}
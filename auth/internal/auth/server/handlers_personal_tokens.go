@@ -0,0 +1,157 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ciameksw/mood-api/auth/internal/auth/repository"
+	"github.com/ciameksw/mood-api/auth/internal/auth/token"
+	"github.com/ciameksw/mood-api/pkg/httputil"
+)
+
+type createPersonalTokenInput struct {
+	Name          string   `json:"name" validate:"required,max=100"`
+	Scopes        []string `json:"scopes" validate:"required,min=1,dive,required"`
+	ExpiresInDays int      `json:"expiresInDays" validate:"required,min=1,max=365"`
+}
+
+type createPersonalTokenResponse struct {
+	repository.PersonalAccessToken
+	Token string `json:"token"`
+}
+
+func (s *Server) handleCreatePersonalToken(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Creating personal access token")
+
+	userID, err := s.getUserIDFromToken(r)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, err.Error(), nil, http.StatusUnauthorized)
+		return
+	}
+
+	var input createPersonalTokenInput
+	err = json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Invalid request payload", err, http.StatusBadRequest)
+		return
+	}
+
+	err = s.Validator.Struct(input)
+	if err != nil {
+		httputil.HandleValidationError(*s.Logger, w, err)
+		return
+	}
+
+	for _, scope := range input.Scopes {
+		if !token.IsValidScope(scope) {
+			httputil.HandleError(*s.Logger, w, "Invalid scope "+scope+", allowed scopes: "+strings.Join(token.Scopes, ", "), nil, http.StatusBadRequest)
+			return
+		}
+	}
+
+	plain, hash, err := token.GeneratePersonalAccessToken()
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to generate token", err, http.StatusInternalServerError)
+		return
+	}
+
+	expiresAt := time.Now().AddDate(0, 0, input.ExpiresInDays)
+	pat, err := s.DBOperations.CreatePersonalAccessToken(r.Context(), userID, input.Name, hash, input.Scopes, expiresAt)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to store token", err, http.StatusInternalServerError)
+		return
+	}
+
+	s.recordEvent(r, userID, repository.EventPersonalTokenCreated, repository.OutcomeSuccess, fmt.Sprintf("token %d", pat.ID))
+	s.Logger.Info.Printf("Personal access token %d created for user %d", pat.ID, userID)
+	resp := createPersonalTokenResponse{
+		PersonalAccessToken: *pat,
+		Token:               plain,
+	}
+	httputil.WriteData(*s.Logger, w, resp, http.StatusCreated)
+}
+
+func (s *Server) handleGetPersonalTokens(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Getting personal access tokens")
+
+	userID, err := s.getUserIDFromToken(r)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, err.Error(), nil, http.StatusUnauthorized)
+		return
+	}
+
+	tokens, err := s.DBOperations.GetUserPersonalAccessTokens(r.Context(), userID)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to retrieve tokens", err, http.StatusInternalServerError)
+		return
+	}
+
+	httputil.WriteData(*s.Logger, w, tokens, http.StatusOK)
+}
+
+func (s *Server) handleDeletePersonalToken(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Revoking personal access token")
+
+	userID, err := s.getUserIDFromToken(r)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, err.Error(), nil, http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Invalid id parameter", err, http.StatusBadRequest)
+		return
+	}
+
+	err = s.DBOperations.RevokePersonalAccessToken(r.Context(), id, userID)
+	if err != nil {
+		if err.Error() == "personal access token not found" {
+			httputil.HandleError(*s.Logger, w, "Token not found", nil, http.StatusNotFound)
+			return
+		}
+		httputil.HandleError(*s.Logger, w, "Failed to revoke token", err, http.StatusInternalServerError)
+		return
+	}
+
+	s.recordEvent(r, userID, repository.EventPersonalTokenRevoked, repository.OutcomeSuccess, fmt.Sprintf("token %d", id))
+	s.Logger.Info.Printf("Personal access token %d of user %d revoked", id, userID)
+	httputil.WriteSuccessMessage(*s.Logger, w, "Token revoked successfully", http.StatusOK)
+}
+
+// Helper function to authorize a request made with a personal access token
+func (s *Server) authorizePersonalToken(w http.ResponseWriter, r *http.Request, tokenString string) {
+	pat, err := s.DBOperations.GetActivePersonalAccessToken(r.Context(), token.HashRandomToken(tokenString))
+	if err != nil {
+		if err.Error() == "personal access token not found" {
+			httputil.HandleError(*s.Logger, w, "invalid or expired token", nil, http.StatusUnauthorized)
+			return
+		}
+		httputil.HandleError(*s.Logger, w, "Failed to retrieve token", err, http.StatusInternalServerError)
+		return
+	}
+
+	if err := s.DBOperations.TouchPersonalAccessToken(r.Context(), pat.ID); err != nil {
+		s.Logger.Error.Printf("Failed to update token %d: %v", pat.ID, err)
+	}
+
+	// Personal access tokens live for months, so preferences are looked up on every request instead of stored in the token
+	prefs, err := s.DBOperations.GetUserPreferences(r.Context(), pat.UserID)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to retrieve preferences", err, http.StatusInternalServerError)
+		return
+	}
+
+	resp := map[string]interface{}{
+		"userId":    pat.UserID,
+		"tokenType": "personal",
+		"scopes":    pat.Scopes,
+		"timezone":  prefs.Timezone,
+		"weekStart": prefs.WeekStart,
+	}
+	httputil.WriteData(*s.Logger, w, resp, http.StatusOK)
+}
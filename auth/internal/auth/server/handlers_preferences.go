@@ -0,0 +1,88 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/ciameksw/mood-api/pkg/httputil"
+)
+
+func (s *Server) handleGetPreferences(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Getting user preferences")
+
+	userID, err := s.getUserIDFromToken(r)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, err.Error(), nil, http.StatusUnauthorized)
+		return
+	}
+
+	prefs, err := s.DBOperations.GetUserPreferences(r.Context(), userID)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to retrieve preferences", err, http.StatusInternalServerError)
+		return
+	}
+
+	httputil.WriteData(*s.Logger, w, prefs, http.StatusOK)
+}
+
+type updatePreferencesInput struct {
+	Timezone        *string `json:"timezone" validate:"omitnil,timezone"`
+	Locale          *string `json:"locale" validate:"omitnil,bcp47_language_tag"`
+	WeekStart       *string `json:"weekStart" validate:"omitnil,oneof=monday saturday sunday"`
+	ReminderEnabled *bool   `json:"reminderEnabled"`
+	ReminderTime    *string `json:"reminderTime" validate:"omitnil,datetime=15:04"`
+}
+
+func (s *Server) handleUpdatePreferences(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Updating user preferences")
+
+	userID, err := s.getUserIDFromToken(r)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, err.Error(), nil, http.StatusUnauthorized)
+		return
+	}
+
+	var input updatePreferencesInput
+	err = json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Invalid request payload", err, http.StatusBadRequest)
+		return
+	}
+
+	err = s.Validator.Struct(input)
+	if err != nil {
+		httputil.HandleValidationError(*s.Logger, w, err)
+		return
+	}
+
+	// Fields left out of the request keep their current value
+	prefs, err := s.DBOperations.GetUserPreferences(r.Context(), userID)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to retrieve preferences", err, http.StatusInternalServerError)
+		return
+	}
+	if input.Timezone != nil {
+		prefs.Timezone = *input.Timezone
+	}
+	if input.Locale != nil {
+		prefs.Locale = *input.Locale
+	}
+	if input.WeekStart != nil {
+		prefs.WeekStart = *input.WeekStart
+	}
+	if input.ReminderEnabled != nil {
+		prefs.ReminderEnabled = *input.ReminderEnabled
+	}
+	if input.ReminderTime != nil {
+		prefs.ReminderTime = *input.ReminderTime
+	}
+
+	err = s.DBOperations.SaveUserPreferences(r.Context(), userID, prefs)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to update preferences", err, http.StatusInternalServerError)
+		return
+	}
+
+	s.Logger.Info.Printf("Preferences updated for user %d", userID)
+	httputil.WriteData(*s.Logger, w, prefs, http.StatusOK)
+}
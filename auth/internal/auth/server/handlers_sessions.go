@@ -0,0 +1,80 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/ciameksw/mood-api/auth/internal/auth/repository"
+	"github.com/ciameksw/mood-api/pkg/httputil"
+)
+
+type sessionResponse struct {
+	repository.Session
+	Current bool `json:"current"`
+}
+
+func (s *Server) handleGetSessions(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Getting user sessions")
+
+	claims, err := s.getClaimsFromToken(r)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, err.Error(), nil, http.StatusUnauthorized)
+		return
+	}
+
+	sessions, err := s.DBOperations.GetUserSessions(r.Context(), claims.UserID)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to retrieve sessions", err, http.StatusInternalServerError)
+		return
+	}
+
+	resp := make([]sessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		resp = append(resp, sessionResponse{
+			Session: session,
+			Current: session.ID == claims.SessionID,
+		})
+	}
+	httputil.WriteData(*s.Logger, w, resp, http.StatusOK)
+}
+
+func (s *Server) handleDeleteSession(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Revoking user session")
+
+	userID, err := s.getUserIDFromToken(r)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, err.Error(), nil, http.StatusUnauthorized)
+		return
+	}
+
+	sessionID := r.PathValue("id")
+	if sessionID == "" {
+		httputil.HandleError(*s.Logger, w, "Missing id parameter", nil, http.StatusBadRequest)
+		return
+	}
+
+	// Sessions of other users are reported as missing so their ids cannot be probed
+	ownerID, err := s.DBOperations.GetSessionOwner(r.Context(), sessionID)
+	if err != nil {
+		if err.Error() == "session not found" {
+			httputil.HandleError(*s.Logger, w, "Session not found", nil, http.StatusNotFound)
+			return
+		}
+		httputil.HandleError(*s.Logger, w, "Failed to retrieve session", err, http.StatusInternalServerError)
+		return
+	}
+	if ownerID != userID {
+		httputil.HandleError(*s.Logger, w, "Session not found", nil, http.StatusNotFound)
+		return
+	}
+
+	// The session id is the refresh token family, revoking it kills its refresh and access tokens
+	err = s.DBOperations.RevokeRefreshTokenFamily(r.Context(), sessionID)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to revoke session", err, http.StatusInternalServerError)
+		return
+	}
+
+	s.recordEvent(r, userID, repository.EventSessionRevoked, repository.OutcomeSuccess, "session "+sessionID)
+	s.Logger.Info.Printf("Session %s of user %d revoked", sessionID, userID)
+	httputil.WriteSuccessMessage(*s.Logger, w, "Session revoked successfully", http.StatusOK)
+}
@@ -0,0 +1,250 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/ciameksw/mood-api/auth/internal/auth/repository"
+	"github.com/ciameksw/mood-api/auth/internal/auth/token"
+	"github.com/ciameksw/mood-api/pkg/httputil"
+)
+
+type refreshInput struct {
+	RefreshToken string `json:"refreshToken" validate:"required"`
+}
+
+func (s *Server) handleRefresh(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Refreshing token")
+	var input refreshInput
+
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Invalid request payload", err, http.StatusBadRequest)
+		return
+	}
+
+	err = s.Validator.Struct(input)
+	if err != nil {
+		httputil.HandleValidationError(*s.Logger, w, err)
+		return
+	}
+
+	stored, err := s.DBOperations.GetRefreshTokenByHash(r.Context(), token.HashRandomToken(input.RefreshToken))
+	if err != nil {
+		if err.Error() == "refresh token not found" {
+			httputil.HandleError(*s.Logger, w, "Invalid refresh token", nil, http.StatusUnauthorized)
+			return
+		}
+		httputil.HandleError(*s.Logger, w, "Failed to retrieve refresh token", err, http.StatusInternalServerError)
+		return
+	}
+
+	// A rotated token being presented again means it leaked, so the whole family is revoked
+	if stored.RevokedAt != nil {
+		s.revokeRefreshTokenFamily(r.Context(), stored)
+		httputil.HandleError(*s.Logger, w, "Invalid refresh token", nil, http.StatusUnauthorized)
+		return
+	}
+
+	if time.Now().After(stored.ExpiresAt) {
+		httputil.HandleError(*s.Logger, w, "Refresh token expired", nil, http.StatusUnauthorized)
+		return
+	}
+
+	refreshToken, refreshHash, err := token.GenerateRandomToken()
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to generate refresh token", err, http.StatusInternalServerError)
+		return
+	}
+
+	expiresAt := time.Now().Add(s.Config.RefreshTokenTTL)
+	err = s.DBOperations.RotateRefreshToken(r.Context(), stored, refreshHash, expiresAt)
+	if err != nil {
+		if err.Error() == "refresh token already used" {
+			s.revokeRefreshTokenFamily(r.Context(), stored)
+			httputil.HandleError(*s.Logger, w, "Invalid refresh token", nil, http.StatusUnauthorized)
+			return
+		}
+		httputil.HandleError(*s.Logger, w, "Failed to rotate refresh token", err, http.StatusInternalServerError)
+		return
+	}
+
+	// The access token is only stored once the rotation succeeded, so a failed refresh leaves no unused token behind
+	accessToken, err := s.issueAccessToken(r.Context(), stored.UserID, stored.FamilyID)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to generate JWT", err, http.StatusInternalServerError)
+		return
+	}
+
+	err = s.DBOperations.ExtendSession(r.Context(), stored.FamilyID, r.UserAgent(), clientIP(r), expiresAt)
+	if err != nil {
+		s.Logger.Error.Printf("Failed to update session %s: %v", stored.FamilyID, err)
+	}
+
+	resp := loginResponse{
+		Token:        accessToken,
+		RefreshToken: refreshToken,
+	}
+	httputil.WriteData(*s.Logger, w, resp, http.StatusOK)
+}
+
+func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Logging out user")
+
+	claims, err := s.getClaimsFromToken(r)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, err.Error(), nil, http.StatusUnauthorized)
+		return
+	}
+
+	familyID, err := s.DBOperations.GetAccessTokenFamily(r.Context(), claims.ID)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to retrieve token", err, http.StatusInternalServerError)
+		return
+	}
+
+	// Revoking the family also kills the refresh token of this login
+	err = s.DBOperations.RevokeRefreshTokenFamily(r.Context(), familyID)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to revoke token", err, http.StatusInternalServerError)
+		return
+	}
+
+	s.recordEvent(r, claims.UserID, repository.EventLogout, repository.OutcomeSuccess, "")
+	s.Logger.Info.Printf("User logged out: %d", claims.UserID)
+	httputil.WriteSuccessMessage(*s.Logger, w, "Logged out successfully", http.StatusOK)
+}
+
+func (s *Server) handleLogoutAll(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Logging out user from all sessions")
+
+	userID, err := s.getUserIDFromToken(r)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, err.Error(), nil, http.StatusUnauthorized)
+		return
+	}
+
+	err = s.DBOperations.RevokeUserTokens(r.Context(), userID)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to revoke tokens", err, http.StatusInternalServerError)
+		return
+	}
+
+	s.recordEvent(r, userID, repository.EventLogoutAll, repository.OutcomeSuccess, "")
+	s.Logger.Info.Printf("User logged out from all sessions: %d", userID)
+	httputil.WriteSuccessMessage(*s.Logger, w, "Logged out from all sessions successfully", http.StatusOK)
+}
+
+func (s *Server) handleJWKS(w http.ResponseWriter, r *http.Request) {
+	set, err := s.Keys.JWKS()
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to build JWKS", err, http.StatusInternalServerError)
+		return
+	}
+
+	// Verifiers may cache the keys for a short while, rotation keeps old keys published long enough
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	httputil.WriteData(*s.Logger, w, set, http.StatusOK)
+}
+
+type revocationsResponse struct {
+	AsOf    time.Time                 `json:"asOf"`
+	Revoked []repository.RevokedToken `json:"revoked"`
+}
+
+// handleGetRevocations serves the revocation feed used by verifiers that check tokens locally
+func (s *Server) handleGetRevocations(w http.ResponseWriter, r *http.Request) {
+	since := time.Time{}
+	if sinceStr := r.URL.Query().Get("since"); sinceStr != "" {
+		parsed, err := time.Parse(time.RFC3339Nano, sinceStr)
+		if err != nil {
+			httputil.HandleError(*s.Logger, w, "since must be an RFC 3339 timestamp", err, http.StatusBadRequest)
+			return
+		}
+		since = parsed
+	}
+
+	asOf := time.Now()
+	revoked, err := s.DBOperations.GetRevokedTokensSince(r.Context(), since)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to retrieve revoked tokens", err, http.StatusInternalServerError)
+		return
+	}
+
+	resp := revocationsResponse{
+		AsOf:    asOf,
+		Revoked: revoked,
+	}
+	httputil.WriteData(*s.Logger, w, resp, http.StatusOK)
+}
+
+// Helper function to start a session for the requesting client and issue its first access and refresh token.
+// The refresh token family id doubles as the session id.
+func (s *Server) issueTokens(r *http.Request, userID int) (*loginResponse, error) {
+	ctx := r.Context()
+	familyID, err := token.GenerateID()
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt := time.Now().Add(s.Config.RefreshTokenTTL)
+	err = s.DBOperations.CreateSession(ctx, familyID, userID, r.UserAgent(), clientIP(r), expiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, err := s.issueAccessToken(ctx, userID, familyID)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, refreshHash, err := token.GenerateRandomToken()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.DBOperations.CreateRefreshToken(ctx, userID, familyID, refreshHash, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &loginResponse{
+		Token:        accessToken,
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+// Helper function to generate an access token with the user's current roles and preferences and record it in the revocation store
+func (s *Server) issueAccessToken(ctx context.Context, userID int, familyID string) (string, error) {
+	roles, err := s.DBOperations.GetUserRoles(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+
+	prefs, err := s.DBOperations.GetUserPreferences(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+
+	accessToken, claims, err := token.GenerateJWT(userID, familyID, roles, prefs.Timezone, prefs.WeekStart, s.Keys)
+	if err != nil {
+		return "", err
+	}
+
+	err = s.DBOperations.CreateAccessToken(ctx, claims.ID, userID, familyID, claims.IssuedAt.Time, claims.ExpiresAt.Time)
+	if err != nil {
+		return "", err
+	}
+
+	return accessToken, nil
+}
+
+// Helper function to revoke a refresh token family after reuse was detected
+func (s *Server) revokeRefreshTokenFamily(ctx context.Context, rt *repository.RefreshToken) {
+	s.Logger.Error.Printf("Refresh token reuse detected for user %d, revoking family %s", rt.UserID, rt.FamilyID)
+	if err := s.DBOperations.RevokeRefreshTokenFamily(ctx, rt.FamilyID); err != nil {
+		s.Logger.Error.Printf("Failed to revoke refresh token family %s: %v", rt.FamilyID, err)
+	}
+}
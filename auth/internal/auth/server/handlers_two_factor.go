@@ -0,0 +1,433 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/ciameksw/mood-api/auth/internal/auth/repository"
+	"github.com/ciameksw/mood-api/auth/internal/auth/token"
+	"github.com/ciameksw/mood-api/pkg/httputil"
+)
+
+const recoveryCodeCount = 10
+
+type twoFactorChallengeResponse struct {
+	TwoFactorRequired bool   `json:"twoFactorRequired"`
+	ChallengeToken    string `json:"challengeToken"`
+}
+
+type twoFactorSetupResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauthUri"`
+}
+
+type recoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+func (s *Server) handleTwoFactorSetup(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Starting two-factor setup")
+
+	userID, err := s.getUserIDFromToken(r)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, err.Error(), nil, http.StatusUnauthorized)
+		return
+	}
+
+	if s.Secrets == nil {
+		httputil.HandleError(*s.Logger, w, "Two-factor authentication is not available", nil, http.StatusServiceUnavailable)
+		return
+	}
+
+	user, err := s.DBOperations.GetUserByID(r.Context(), userID)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to retrieve user", err, http.StatusInternalServerError)
+		return
+	}
+
+	secret, err := token.GenerateTOTPSecret()
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to generate secret", err, http.StatusInternalServerError)
+		return
+	}
+
+	encrypted, err := s.Secrets.Encrypt(secret)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to encrypt secret", err, http.StatusInternalServerError)
+		return
+	}
+
+	err = s.DBOperations.SavePendingTwoFactor(r.Context(), userID, encrypted)
+	if err != nil {
+		if err.Error() == "two factor already enabled" {
+			httputil.HandleError(*s.Logger, w, "Two-factor authentication is already enabled", nil, http.StatusConflict)
+			return
+		}
+		httputil.HandleError(*s.Logger, w, "Failed to save secret", err, http.StatusInternalServerError)
+		return
+	}
+
+	resp := twoFactorSetupResponse{
+		Secret:     secret,
+		OTPAuthURI: token.TOTPURI(s.Config.TOTPIssuer, user.Email, secret),
+	}
+	httputil.WriteData(*s.Logger, w, resp, http.StatusOK)
+}
+
+type twoFactorConfirmInput struct {
+	Code string `json:"code" validate:"required,len=6,numeric"`
+}
+
+func (s *Server) handleTwoFactorConfirm(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Confirming two-factor setup")
+
+	userID, err := s.getUserIDFromToken(r)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, err.Error(), nil, http.StatusUnauthorized)
+		return
+	}
+
+	var input twoFactorConfirmInput
+	err = json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Invalid request payload", err, http.StatusBadRequest)
+		return
+	}
+
+	err = s.Validator.Struct(input)
+	if err != nil {
+		httputil.HandleValidationError(*s.Logger, w, err)
+		return
+	}
+
+	if s.Secrets == nil {
+		httputil.HandleError(*s.Logger, w, "Two-factor authentication is not available", nil, http.StatusServiceUnavailable)
+		return
+	}
+
+	tf, err := s.DBOperations.GetTwoFactor(r.Context(), userID)
+	if err != nil {
+		if err.Error() == "two factor not found" {
+			httputil.HandleError(*s.Logger, w, "Two-factor setup has not been started", nil, http.StatusBadRequest)
+			return
+		}
+		httputil.HandleError(*s.Logger, w, "Failed to retrieve two-factor settings", err, http.StatusInternalServerError)
+		return
+	}
+
+	if tf.ConfirmedAt != nil {
+		httputil.HandleError(*s.Logger, w, "Two-factor authentication is already enabled", nil, http.StatusConflict)
+		return
+	}
+
+	secret, err := s.Secrets.Decrypt(tf.SecretEncrypted)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to decrypt secret", err, http.StatusInternalServerError)
+		return
+	}
+
+	step, ok := token.ValidateTOTP(secret, input.Code, time.Now())
+	if !ok {
+		httputil.HandleError(*s.Logger, w, "Invalid code", nil, http.StatusBadRequest)
+		return
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to generate recovery codes", err, http.StatusInternalServerError)
+		return
+	}
+
+	err = s.DBOperations.ConfirmTwoFactor(r.Context(), userID, step, hashes)
+	if err != nil {
+		if err.Error() == "two factor already enabled" {
+			httputil.HandleError(*s.Logger, w, "Two-factor authentication is already enabled", nil, http.StatusConflict)
+			return
+		}
+		httputil.HandleError(*s.Logger, w, "Failed to enable two-factor authentication", err, http.StatusInternalServerError)
+		return
+	}
+
+	s.recordEvent(r, userID, repository.EventTwoFactorEnabled, repository.OutcomeSuccess, "")
+	s.Logger.Info.Printf("Two-factor authentication enabled for user %d", userID)
+	httputil.WriteData(*s.Logger, w, recoveryCodesResponse{RecoveryCodes: codes}, http.StatusOK)
+}
+
+type twoFactorVerifyInput struct {
+	ChallengeToken string `json:"challengeToken" validate:"required"`
+	Code           string `json:"code" validate:"required_without=RecoveryCode"`
+	RecoveryCode   string `json:"recoveryCode" validate:"required_without=Code"`
+}
+
+func (s *Server) handleTwoFactorVerify(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Verifying two-factor login")
+	var input twoFactorVerifyInput
+
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Invalid request payload", err, http.StatusBadRequest)
+		return
+	}
+
+	err = s.Validator.Struct(input)
+	if err != nil {
+		httputil.HandleValidationError(*s.Logger, w, err)
+		return
+	}
+
+	claims, err := token.ValidateChallengeJWT(input.ChallengeToken, token.PurposeTwoFactor, s.Keys)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Invalid or expired challenge token", nil, http.StatusUnauthorized)
+		return
+	}
+
+	user, err := s.DBOperations.GetUserByID(r.Context(), claims.UserID)
+	if err != nil {
+		if err.Error() == "user not found" {
+			httputil.HandleError(*s.Logger, w, "Invalid or expired challenge token", nil, http.StatusUnauthorized)
+			return
+		}
+		httputil.HandleError(*s.Logger, w, "Failed to retrieve user", err, http.StatusInternalServerError)
+		return
+	}
+
+	// The account was deleted after the challenge was issued
+	if user.DeletedAt != nil {
+		httputil.HandleError(*s.Logger, w, "Invalid or expired challenge token", nil, http.StatusUnauthorized)
+		return
+	}
+
+	// Codes are guessable, so failures count towards the same lockout as passwords
+	ip := clientIP(r)
+	if !s.checkLoginLockout(r.Context(), w, user.Email, ip) {
+		s.recordEvent(r, user.ID, repository.EventLoginLocked, repository.OutcomeFailure, "two-factor verification")
+		return
+	}
+
+	ok, err := s.verifySecondFactor(r.Context(), claims.UserID, input.Code, input.RecoveryCode)
+	if err != nil {
+		// Two-factor authentication was disabled after the challenge was issued
+		if err.Error() == "two factor not enabled" {
+			httputil.HandleError(*s.Logger, w, "Invalid or expired challenge token", nil, http.StatusUnauthorized)
+			return
+		}
+		httputil.HandleError(*s.Logger, w, "Failed to verify code", err, http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		s.recordLoginFailure(r.Context(), user.Email, ip)
+		s.recordEvent(r, user.ID, repository.EventTwoFactorLogin, repository.OutcomeFailure, "invalid code")
+		httputil.HandleError(*s.Logger, w, "Invalid code", nil, http.StatusUnauthorized)
+		return
+	}
+
+	resp, err := s.issueTokens(r, claims.UserID)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to generate tokens", err, http.StatusInternalServerError)
+		return
+	}
+
+	s.resetAccountLockout(r.Context(), user.Email)
+	detail := ""
+	if input.Code == "" {
+		detail = "recovery code used"
+	}
+	s.recordEvent(r, user.ID, repository.EventTwoFactorLogin, repository.OutcomeSuccess, detail)
+	s.Logger.Info.Printf("User logged in with two-factor authentication: %d", claims.UserID)
+	httputil.WriteData(*s.Logger, w, resp, http.StatusOK)
+}
+
+type twoFactorCodeInput struct {
+	Code         string `json:"code" validate:"required_without=RecoveryCode"`
+	RecoveryCode string `json:"recoveryCode" validate:"required_without=Code"`
+}
+
+func (s *Server) handleRegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Regenerating recovery codes")
+
+	userID, ok := s.authorizeSecondFactor(w, r)
+	if !ok {
+		return
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to generate recovery codes", err, http.StatusInternalServerError)
+		return
+	}
+
+	err = s.DBOperations.ReplaceRecoveryCodes(r.Context(), userID, hashes)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to store recovery codes", err, http.StatusInternalServerError)
+		return
+	}
+
+	s.recordEvent(r, userID, repository.EventRecoveryCodesRegenerated, repository.OutcomeSuccess, "")
+	s.Logger.Info.Printf("Recovery codes regenerated for user %d", userID)
+	httputil.WriteData(*s.Logger, w, recoveryCodesResponse{RecoveryCodes: codes}, http.StatusOK)
+}
+
+func (s *Server) handleTwoFactorDisable(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Disabling two-factor authentication")
+
+	userID, ok := s.authorizeSecondFactor(w, r)
+	if !ok {
+		return
+	}
+
+	err := s.DBOperations.DeleteTwoFactor(r.Context(), userID)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to disable two-factor authentication", err, http.StatusInternalServerError)
+		return
+	}
+
+	s.recordEvent(r, userID, repository.EventTwoFactorDisabled, repository.OutcomeSuccess, "")
+	s.Logger.Info.Printf("Two-factor authentication disabled for user %d", userID)
+	httputil.WriteSuccessMessage(*s.Logger, w, "Two-factor authentication disabled successfully", http.StatusOK)
+}
+
+// Helper function for endpoints that need a logged user to prove possession of the second factor again.
+// It writes the error response itself and reports whether the handler may continue.
+func (s *Server) authorizeSecondFactor(w http.ResponseWriter, r *http.Request) (int, bool) {
+	var input twoFactorCodeInput
+
+	userID, err := s.getUserIDFromToken(r)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, err.Error(), nil, http.StatusUnauthorized)
+		return 0, false
+	}
+
+	err = json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Invalid request payload", err, http.StatusBadRequest)
+		return 0, false
+	}
+
+	err = s.Validator.Struct(input)
+	if err != nil {
+		httputil.HandleValidationError(*s.Logger, w, err)
+		return 0, false
+	}
+
+	user, err := s.DBOperations.GetUserByID(r.Context(), userID)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to retrieve user", err, http.StatusInternalServerError)
+		return 0, false
+	}
+
+	ip := clientIP(r)
+	if !s.checkLoginLockout(r.Context(), w, user.Email, ip) {
+		return 0, false
+	}
+
+	ok, err := s.verifySecondFactor(r.Context(), userID, input.Code, input.RecoveryCode)
+	if err != nil {
+		if err.Error() == "two factor not enabled" {
+			httputil.HandleError(*s.Logger, w, "Two-factor authentication is not enabled", nil, http.StatusBadRequest)
+			return 0, false
+		}
+		httputil.HandleError(*s.Logger, w, "Failed to verify code", err, http.StatusInternalServerError)
+		return 0, false
+	}
+	if !ok {
+		s.recordLoginFailure(r.Context(), user.Email, ip)
+		httputil.HandleError(*s.Logger, w, "Invalid code", nil, http.StatusUnauthorized)
+		return 0, false
+	}
+
+	return userID, true
+}
+
+// Helper function to check a TOTP code or, when no code is given, a recovery code.
+// Accepted codes are consumed so they cannot be replayed.
+func (s *Server) verifySecondFactor(ctx context.Context, userID int, code, recoveryCode string) (bool, error) {
+	tf, err := s.getEnabledTwoFactor(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+
+	if code == "" {
+		err = s.DBOperations.UseRecoveryCode(ctx, userID, token.HashRecoveryCode(recoveryCode))
+		if err != nil {
+			if err.Error() == "recovery code not found" {
+				return false, nil
+			}
+			return false, err
+		}
+
+		s.Logger.Info.Printf("Recovery code used by user %d", userID)
+		return true, nil
+	}
+
+	if s.Secrets == nil {
+		return false, errors.New("two-factor secrets cannot be decrypted without TOTP_ENCRYPTION_KEY")
+	}
+
+	secret, err := s.Secrets.Decrypt(tf.SecretEncrypted)
+	if err != nil {
+		return false, err
+	}
+
+	step, ok := token.ValidateTOTP(secret, code, time.Now())
+	if !ok || step <= tf.LastUsedStep {
+		return false, nil
+	}
+
+	err = s.DBOperations.UseTOTPStep(ctx, userID, step)
+	if err != nil {
+		if err.Error() == "code already used" {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Helper function to check whether the user has to pass a second factor at login
+func (s *Server) isTwoFactorEnabled(ctx context.Context, userID int) (bool, error) {
+	_, err := s.getEnabledTwoFactor(ctx, userID)
+	if err != nil {
+		if err.Error() == "two factor not enabled" {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Helper function to retrieve confirmed two-factor settings, pending enrollments count as not enabled
+func (s *Server) getEnabledTwoFactor(ctx context.Context, userID int) (*repository.TwoFactor, error) {
+	tf, err := s.DBOperations.GetTwoFactor(ctx, userID)
+	if err != nil {
+		if err.Error() == "two factor not found" {
+			return nil, errors.New("two factor not enabled")
+		}
+		return nil, err
+	}
+
+	if tf.ConfirmedAt == nil {
+		return nil, errors.New("two factor not enabled")
+	}
+
+	return tf, nil
+}
+
+// Helper function to generate recovery codes together with the hashes to store
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes, err := token.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = token.HashRecoveryCode(code)
+	}
+
+	return codes, hashes, nil
+}
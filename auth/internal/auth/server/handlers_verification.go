@@ -0,0 +1,152 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/ciameksw/mood-api/auth/internal/auth/mailer"
+	"github.com/ciameksw/mood-api/auth/internal/auth/repository"
+	"github.com/ciameksw/mood-api/auth/internal/auth/token"
+	"github.com/ciameksw/mood-api/pkg/httputil"
+)
+
+func (s *Server) handleVerifyEmail(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Verifying email")
+
+	verificationToken := r.URL.Query().Get("token")
+	if verificationToken == "" {
+		httputil.HandleError(*s.Logger, w, "Missing token parameter", nil, http.StatusBadRequest)
+		return
+	}
+
+	evt, err := s.DBOperations.GetEmailVerificationToken(r.Context(), token.HashRandomToken(verificationToken))
+	if err != nil {
+		if err.Error() == "verification token not found" {
+			httputil.HandleError(*s.Logger, w, "Invalid or expired verification token", nil, http.StatusBadRequest)
+			return
+		}
+		httputil.HandleError(*s.Logger, w, "Failed to retrieve verification token", err, http.StatusInternalServerError)
+		return
+	}
+
+	if evt.UsedAt != nil || time.Now().After(evt.ExpiresAt) {
+		httputil.HandleError(*s.Logger, w, "Invalid or expired verification token", nil, http.StatusBadRequest)
+		return
+	}
+
+	err = s.DBOperations.VerifyEmail(r.Context(), evt)
+	if err != nil {
+		if err.Error() == "verification token already used" || err.Error() == "email has changed" {
+			httputil.HandleError(*s.Logger, w, "Invalid or expired verification token", nil, http.StatusBadRequest)
+			return
+		}
+		httputil.HandleError(*s.Logger, w, "Failed to verify email", err, http.StatusInternalServerError)
+		return
+	}
+
+	s.recordEvent(r, evt.UserID, repository.EventEmailVerified, repository.OutcomeSuccess, "")
+	s.Logger.Info.Printf("Email verified for user %d", evt.UserID)
+	s.grantBootstrapAdmin(r.Context(), evt.UserID, evt.Email, true)
+	httputil.WriteSuccessMessage(*s.Logger, w, "Email verified successfully", http.StatusOK)
+}
+
+type resendVerificationInput struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+func (s *Server) handleResendVerification(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Resending verification email")
+	var input resendVerificationInput
+
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Invalid request payload", err, http.StatusBadRequest)
+		return
+	}
+
+	err = s.Validator.Struct(input)
+	if err != nil {
+		httputil.HandleValidationError(*s.Logger, w, err)
+		return
+	}
+
+	// Like forgot password, the response does not reveal whether the email is registered, verified or throttled
+	go s.resendVerification(input.Email)
+
+	httputil.WriteSuccessMessage(*s.Logger, w, "If an unverified account with that email exists, a verification link has been sent", http.StatusOK)
+}
+
+// Helper function to resend the verification email unless the account is verified or was emailed recently
+func (s *Server) resendVerification(email string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	user, err := s.DBOperations.GetUserByEmail(ctx, email)
+	if err != nil {
+		if err.Error() != "user not found" {
+			s.Logger.Error.Printf("Failed to look up user for verification: %v", err)
+		}
+		return
+	}
+
+	if user.VerifiedAt != nil {
+		return
+	}
+
+	lastSentAt, err := s.DBOperations.GetLastEmailVerificationSentAt(ctx, user.ID)
+	if err != nil {
+		s.Logger.Error.Printf("Failed to check last verification email: %v", err)
+		return
+	}
+	if lastSentAt != nil && time.Since(*lastSentAt) < s.Config.EmailVerificationResendInterval {
+		s.Logger.Info.Printf("Verification email for user %d throttled", user.ID)
+		return
+	}
+
+	s.sendVerification(ctx, user)
+}
+
+// Helper function to send a verification email in the background after the user's email was set
+func (s *Server) sendVerificationAsync(userID int) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		user, err := s.DBOperations.GetUserByID(ctx, userID)
+		if err != nil {
+			s.Logger.Error.Printf("Failed to look up user for verification: %v", err)
+			return
+		}
+
+		s.sendVerification(ctx, user)
+	}()
+}
+
+// Helper function to create a verification token for the user's current email and send it
+func (s *Server) sendVerification(ctx context.Context, user *repository.User) {
+	verificationToken, verificationHash, err := token.GenerateRandomToken()
+	if err != nil {
+		s.Logger.Error.Printf("Failed to generate verification token: %v", err)
+		return
+	}
+
+	err = s.DBOperations.CreateEmailVerificationToken(ctx, user.ID, user.Email, verificationHash, time.Now().Add(s.Config.EmailVerificationTTL))
+	if err != nil {
+		s.Logger.Error.Printf("Failed to store verification token: %v", err)
+		return
+	}
+
+	link := s.Config.AppBaseURL + "/verify-email?token=" + url.QueryEscape(verificationToken)
+	s.sendMail(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email",
+		Body: fmt.Sprintf("Hi %s,\n\nPlease confirm this email address for your account by opening the link below:\n\n%s\n\nThe link expires in %s.",
+			user.Username, link, s.Config.EmailVerificationTTL),
+	})
+
+	s.Logger.Info.Printf("Verification email sent to user %d", user.ID)
+}
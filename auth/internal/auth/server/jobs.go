@@ -0,0 +1,43 @@
+package server
+
+import (
+	"context"
+	"time"
+)
+
+// StartJobs launches the periodic background jobs until ctx is cancelled
+func (s *Server) StartJobs(ctx context.Context) {
+	go s.runPeriodically(ctx, "token cleanup", time.Hour, s.cleanupExpiredTokens)
+	go s.runPeriodically(ctx, "account purge", time.Hour, s.purgeDeletedAccounts)
+	go s.runPeriodically(ctx, "auth event retention", time.Hour, s.cleanupAuthEvents)
+}
+
+func (s *Server) cleanupExpiredTokens(ctx context.Context) error {
+	deleted, err := s.DBOperations.DeleteExpiredTokens(ctx)
+	if err != nil {
+		return err
+	}
+
+	if deleted > 0 {
+		s.Logger.Info.Printf("Deleted %d expired tokens", deleted)
+	}
+	return nil
+}
+
+// Helper function to run a job on a fixed interval
+func (s *Server) runPeriodically(ctx context.Context, name string, interval time.Duration, job func(context.Context) error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := job(ctx); err != nil {
+			s.Logger.Error.Printf("Background job %s failed: %v", name, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
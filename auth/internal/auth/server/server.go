@@ -5,7 +5,14 @@ import (
 	"net/http"
 
 	"github.com/ciameksw/mood-api/auth/internal/auth/config"
+	"github.com/ciameksw/mood-api/auth/internal/auth/lockout"
+	"github.com/ciameksw/mood-api/auth/internal/auth/mailer"
+	"github.com/ciameksw/mood-api/auth/internal/auth/oidc"
+	"github.com/ciameksw/mood-api/auth/internal/auth/passwordpolicy"
 	"github.com/ciameksw/mood-api/auth/internal/auth/repository"
+	"github.com/ciameksw/mood-api/auth/internal/auth/token"
+	"github.com/ciameksw/mood-api/auth/internal/auth/userdata"
+	"github.com/ciameksw/mood-api/pkg/httputil"
 	"github.com/ciameksw/mood-api/pkg/logger"
 	"github.com/ciameksw/mood-api/pkg/postgres"
 	"github.com/go-playground/validator/v10"
@@ -16,16 +23,58 @@ type Server struct {
 	Config       *config.Config
 	DBOperations *repository.DBOperations
 	Validator    *validator.Validate
-	httpServer   *http.Server
+	Keys         *token.KeySet
+	Secrets      *token.SecretCipher
+	Passwords    *token.PasswordHasher
+	Policy       *passwordpolicy.Policy
+	Mailer       mailer.Mailer
+	// Failed logins are counted per account and per client IP
+	AccountLockout *lockout.Limiter
+	IPLockout      *lockout.Limiter
+	// Login link requests are counted per email
+	MagicLinkLimiter *lockout.Limiter
+	OIDCProviders    map[string]*oidc.Provider
+	// Erases the user's data in the mood and advice services when an account is purged
+	UserData   *userdata.Client
+	httpServer *http.Server
 }
 
-func NewServer(log *logger.Logger, cfg *config.Config, pg *postgres.PostgresDB) *Server {
-	return &Server{
+func NewServer(log *logger.Logger, cfg *config.Config, pg *postgres.PostgresDB, keys *token.KeySet, secrets *token.SecretCipher, passwords *token.PasswordHasher, policy *passwordpolicy.Policy) *Server {
+	s := &Server{
 		Logger:       log,
 		Config:       cfg,
 		DBOperations: &repository.DBOperations{Postgres: pg},
-		Validator:    validator.New(),
+		Validator:    httputil.NewValidator(),
+		Keys:         keys,
+		Secrets:      secrets,
+		Passwords:    passwords,
+		Policy:       policy,
+		Mailer:       mailer.NewMailer(cfg),
 	}
+	s.OIDCProviders = oidc.NewProviders(cfg)
+	s.UserData = userdata.NewClient(cfg)
+
+	store := lockout.NewStore(cfg)
+	s.AccountLockout = lockout.NewLimiter(store, "account:", lockout.Policy{
+		Threshold:   cfg.LoginMaxAccountFailures,
+		BaseLockout: cfg.LoginLockoutBase,
+		MaxLockout:  cfg.LoginLockoutMax,
+		Window:      cfg.LoginFailureWindow,
+	})
+	s.IPLockout = lockout.NewLimiter(store, "ip:", lockout.Policy{
+		Threshold:   cfg.LoginMaxIPFailures,
+		BaseLockout: cfg.LoginLockoutBase,
+		MaxLockout:  cfg.LoginLockoutMax,
+		Window:      cfg.LoginFailureWindow,
+	})
+	s.MagicLinkLimiter = lockout.NewLimiter(store, "magic-link:", lockout.Policy{
+		Threshold:   cfg.MagicLinkMaxPerEmail,
+		BaseLockout: cfg.MagicLinkRateWindow,
+		MaxLockout:  cfg.MagicLinkRateWindow,
+		Window:      cfg.MagicLinkRateWindow,
+	})
+
+	return s
 }
 
 func (s *Server) Start() error {
@@ -33,10 +82,43 @@ func (s *Server) Start() error {
 
 	r.HandleFunc("POST /auth/login", s.handleLogin)
 	r.HandleFunc("POST /auth/register", s.handleRegister)
+	r.HandleFunc("POST /auth/refresh", s.handleRefresh)
+	r.HandleFunc("POST /auth/password/forgot", s.handleForgotPassword)
+	r.HandleFunc("POST /auth/password/reset", s.handleResetPassword)
+	r.HandleFunc("POST /auth/password/change", s.handleChangePassword)
+	r.HandleFunc("POST /auth/magic-link", s.handleRequestMagicLink)
+	r.HandleFunc("POST /auth/magic-link/consume", s.handleConsumeMagicLink)
+	r.HandleFunc("GET /auth/verify", s.handleVerifyEmail)
+	r.HandleFunc("POST /auth/verify/resend", s.handleResendVerification)
+	r.HandleFunc("POST /auth/2fa/verify", s.handleTwoFactorVerify)
+	r.HandleFunc("POST /auth/2fa/setup", s.handleTwoFactorSetup)
+	r.HandleFunc("POST /auth/2fa/confirm", s.handleTwoFactorConfirm)
+	r.HandleFunc("POST /auth/2fa/recovery-codes", s.handleRegenerateRecoveryCodes)
+	r.HandleFunc("POST /auth/2fa/disable", s.handleTwoFactorDisable)
+	r.HandleFunc("GET /auth/oidc/providers", s.handleGetOIDCProviders)
+	r.HandleFunc("GET /auth/oidc/{provider}/login", s.handleOIDCLogin)
+	r.HandleFunc("GET /auth/oidc/{provider}/callback", s.handleOIDCCallback)
 	r.HandleFunc("GET /auth/authorize", s.handleAuthorize)
+	r.HandleFunc("GET /auth/.well-known/jwks.json", s.handleJWKS)
+	r.HandleFunc("GET /auth/revocations", s.handleGetRevocations)
+	r.HandleFunc("POST /auth/logout", s.handleLogout)
+	r.HandleFunc("POST /auth/logout-all", s.handleLogoutAll)
+	r.HandleFunc("POST /auth/tokens", s.handleCreatePersonalToken)
+	r.HandleFunc("GET /auth/tokens", s.handleGetPersonalTokens)
+	r.HandleFunc("DELETE /auth/tokens/{id}", s.handleDeletePersonalToken)
+	r.HandleFunc("GET /auth/sessions", s.handleGetSessions)
+	r.HandleFunc("DELETE /auth/sessions/{id}", s.handleDeleteSession)
+	r.HandleFunc("GET /auth/admin/users", s.handleAdminGetUsers)
+	r.HandleFunc("PUT /auth/admin/users/{id}/roles", s.handleAdminSetUserRoles)
+	r.HandleFunc("POST /auth/admin/users/{id}/restore", s.handleAdminRestoreUser)
+	r.HandleFunc("GET /auth/admin/events", s.handleAdminGetEvents)
+	r.HandleFunc("GET /auth/events", s.handleGetEvents)
 	r.HandleFunc("GET /auth/user", s.handleGetUser)
 	r.HandleFunc("PUT /auth/user", s.handleUpdateUser)
+	r.HandleFunc("GET /auth/user/preferences", s.handleGetPreferences)
+	r.HandleFunc("PUT /auth/user/preferences", s.handleUpdatePreferences)
 	r.HandleFunc("DELETE /auth/user", s.handleDeleteUser)
+	r.HandleFunc("POST /auth/user/restore", s.handleRestoreUser)
 
 	r.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
@@ -0,0 +1,222 @@
+package token
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ciameksw/mood-api/pkg/jwks"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	privateKeySuffix = ".pem"
+	publicKeySuffix  = ".pub.pem"
+)
+
+// KeySet holds the key used to sign new tokens and every key still accepted for verification
+type KeySet struct {
+	signingKID string
+	signingKey crypto.Signer
+	publicKeys map[string]crypto.PublicKey
+}
+
+// LoadKeySet reads PEM keys from dir. Files named <kid>.pem hold private keys and
+// files named <kid>.pub.pem hold public keys of retired keys that only verify tokens.
+func LoadKeySet(dir, signingKID string) (*KeySet, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	ks := &KeySet{publicKeys: make(map[string]crypto.PublicKey)}
+	privateKeys := make(map[string]crypto.Signer)
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, privateKeySuffix) {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+
+		if strings.HasSuffix(name, publicKeySuffix) {
+			kid := strings.TrimSuffix(name, publicKeySuffix)
+			pub, err := parsePublicKey(data)
+			if err != nil {
+				return nil, fmt.Errorf("key %s: %w", name, err)
+			}
+			ks.publicKeys[kid] = pub
+			continue
+		}
+
+		kid := strings.TrimSuffix(name, privateKeySuffix)
+		signer, err := parsePrivateKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %w", name, err)
+		}
+		privateKeys[kid] = signer
+		ks.publicKeys[kid] = signer.Public()
+	}
+
+	if len(privateKeys) == 0 {
+		return nil, errors.New("no private keys found in " + dir)
+	}
+
+	// Without an explicit choice the last key in lexical order signs, so date-prefixed kids rotate naturally
+	if signingKID == "" {
+		kids := make([]string, 0, len(privateKeys))
+		for kid := range privateKeys {
+			kids = append(kids, kid)
+		}
+		sort.Strings(kids)
+		signingKID = kids[len(kids)-1]
+	}
+
+	signer, ok := privateKeys[signingKID]
+	if !ok {
+		return nil, errors.New("signing key " + signingKID + " not found")
+	}
+	ks.signingKID = signingKID
+	ks.signingKey = signer
+
+	return ks, nil
+}
+
+// GenerateEphemeralKeySet creates an in-memory Ed25519 key, tokens signed with it do not survive a restart
+func GenerateEphemeralKeySet() (*KeySet, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	kid, err := GenerateID()
+	if err != nil {
+		return nil, err
+	}
+
+	return &KeySet{
+		signingKID: kid,
+		signingKey: priv,
+		publicKeys: map[string]crypto.PublicKey{kid: pub},
+	}, nil
+}
+
+// SigningKID returns the kid of the key used for new tokens
+func (ks *KeySet) SigningKID() string {
+	return ks.signingKID
+}
+
+// JWKS returns the public keys in JWKS format
+func (ks *KeySet) JWKS() (jwks.Set, error) {
+	kids := make([]string, 0, len(ks.publicKeys))
+	for kid := range ks.publicKeys {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+
+	set := jwks.Set{Keys: make([]jwks.Key, 0, len(kids))}
+	for _, kid := range kids {
+		key, err := jwks.FromPublicKey(kid, ks.publicKeys[kid])
+		if err != nil {
+			return jwks.Set{}, err
+		}
+		set.Keys = append(set.Keys, key)
+	}
+
+	return set, nil
+}
+
+func (ks *KeySet) sign(claims jwt.Claims) (string, error) {
+	method, err := signingMethodFor(ks.signingKey.Public())
+	if err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = ks.signingKID
+	return token.SignedString(ks.signingKey)
+}
+
+func (ks *KeySet) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	pub, ok := ks.publicKeys[kid]
+	if !ok {
+		return nil, errors.New("unknown signing key")
+	}
+
+	method, err := signingMethodFor(pub)
+	if err != nil {
+		return nil, err
+	}
+	if token.Method.Alg() != method.Alg() {
+		return nil, jwt.ErrSignatureInvalid
+	}
+
+	return pub, nil
+}
+
+func signingMethodFor(pub crypto.PublicKey) (jwt.SigningMethod, error) {
+	switch pub.(type) {
+	case *rsa.PublicKey:
+		return jwt.SigningMethodRS256, nil
+	case ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA, nil
+	default:
+		return nil, errors.New("unsupported key type")
+	}
+}
+
+func parsePrivateKey(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("invalid PEM data")
+	}
+
+	if block.Type == "RSA PRIVATE KEY" {
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return k, nil
+	case ed25519.PrivateKey:
+		return k, nil
+	default:
+		return nil, errors.New("unsupported private key type")
+	}
+}
+
+func parsePublicKey(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("invalid PEM data")
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := signingMethodFor(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
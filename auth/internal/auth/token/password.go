@@ -1,13 +1,168 @@
 package token
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
 
-func HashPassword(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 14)
-	return string(bytes), err
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	AlgorithmBcrypt   = "bcrypt"
+	AlgorithmArgon2id = "argon2id"
+
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+)
+
+// PasswordParams selects the algorithm and cost used for new password hashes
+type PasswordParams struct {
+	Algorithm  string
+	BcryptCost int
+	// Argon2Memory is in KiB
+	Argon2Memory  uint32
+	Argon2Time    uint32
+	Argon2Threads uint8
 }
 
-func VerifyPassword(password, hash string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
-	return err == nil
+// PasswordHasher hashes passwords in a self-describing format, bcrypt's $2a$ or the argon2id PHC string,
+// so hashes made with older settings keep verifying after the settings change
+type PasswordHasher struct {
+	params PasswordParams
+	// Hash of a random password, verified against when there is no real hash so the timing matches
+	dummyHash string
+}
+
+func NewPasswordHasher(params PasswordParams) (*PasswordHasher, error) {
+	switch params.Algorithm {
+	case AlgorithmBcrypt:
+		if params.BcryptCost < bcrypt.MinCost || params.BcryptCost > bcrypt.MaxCost {
+			return nil, fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+		}
+	case AlgorithmArgon2id:
+		if params.Argon2Memory < 8*uint32(params.Argon2Threads) || params.Argon2Time < 1 || params.Argon2Threads < 1 {
+			return nil, errors.New("argon2id needs a time and threads of at least 1 and at least 8 KiB memory per thread")
+		}
+	default:
+		return nil, errors.New("unsupported password hash algorithm " + params.Algorithm)
+	}
+
+	h := &PasswordHasher{params: params}
+
+	dummyPassword, _, err := GenerateRandomToken()
+	if err != nil {
+		return nil, err
+	}
+	h.dummyHash, err = h.Hash(dummyPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	return h, nil
+}
+
+// Hash hashes a password with the configured algorithm and parameters
+func (h *PasswordHasher) Hash(password string) (string, error) {
+	if h.params.Algorithm == AlgorithmBcrypt {
+		bytes, err := bcrypt.GenerateFromPassword([]byte(password), h.params.BcryptCost)
+		return string(bytes), err
+	}
+
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, h.params.Argon2Time, h.params.Argon2Memory, h.params.Argon2Threads, argon2KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version,
+		h.params.Argon2Memory,
+		h.params.Argon2Time,
+		h.params.Argon2Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// Verify checks a password against a stored hash. needsRehash reports that the hash was made with other settings
+// than the current ones and should be replaced now that the plain password is known.
+func (h *PasswordHasher) Verify(password, hash string) (match bool, needsRehash bool) {
+	switch {
+	case strings.HasPrefix(hash, "$argon2id$"):
+		params, salt, key, err := decodeArgon2Hash(hash)
+		if err != nil {
+			h.VerifyDummy(password)
+			return false, false
+		}
+
+		computed := argon2.IDKey([]byte(password), salt, params.Argon2Time, params.Argon2Memory, params.Argon2Threads, uint32(len(key)))
+		if subtle.ConstantTimeCompare(computed, key) != 1 {
+			return false, false
+		}
+
+		return true, h.params.Algorithm != AlgorithmArgon2id ||
+			params.Argon2Memory != h.params.Argon2Memory ||
+			params.Argon2Time != h.params.Argon2Time ||
+			params.Argon2Threads != h.params.Argon2Threads ||
+			len(key) != argon2KeyLength
+	case strings.HasPrefix(hash, "$2"):
+		if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) != nil {
+			return false, false
+		}
+
+		cost, err := bcrypt.Cost([]byte(hash))
+		return true, err != nil || h.params.Algorithm != AlgorithmBcrypt || cost != h.params.BcryptCost
+	default:
+		// Accounts without a password, such as those created through an identity provider
+		h.VerifyDummy(password)
+		return false, false
+	}
+}
+
+// VerifyDummy spends as long as verifying a real password, for logins where no account or hash exists
+func (h *PasswordHasher) VerifyDummy(password string) {
+	h.Verify(password, h.dummyHash)
+}
+
+// Helper function to parse $argon2id$v=19$m=...,t=...,p=...$salt$key
+func decodeArgon2Hash(hash string) (PasswordParams, []byte, []byte, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return PasswordParams{}, nil, nil, errors.New("invalid argon2id hash")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return PasswordParams{}, nil, nil, err
+	}
+	if version != argon2.Version {
+		return PasswordParams{}, nil, nil, errors.New("unsupported argon2 version")
+	}
+
+	params := PasswordParams{Algorithm: AlgorithmArgon2id}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Argon2Memory, &params.Argon2Time, &params.Argon2Threads); err != nil {
+		return PasswordParams{}, nil, nil, err
+	}
+	if params.Argon2Time < 1 || params.Argon2Threads < 1 {
+		return PasswordParams{}, nil, nil, errors.New("invalid argon2id parameters")
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return PasswordParams{}, nil, nil, err
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return PasswordParams{}, nil, nil, err
+	}
+	if len(key) == 0 {
+		return PasswordParams{}, nil, nil, errors.New("invalid argon2id hash")
+	}
+
+	return params, salt, key, nil
 }
@@ -0,0 +1,40 @@
+package token
+
+import (
+	"slices"
+	"strings"
+)
+
+// PersonalAccessTokenPrefix marks personal access tokens so they can be told apart from JWTs
+const PersonalAccessTokenPrefix = "mpat_"
+
+// Scopes that can be granted to personal access tokens
+const (
+	ScopeMoodRead   = "mood:read"
+	ScopeMoodWrite  = "mood:write"
+	ScopeAdviceRead = "advice:read"
+	ScopeQuoteRead  = "quote:read"
+)
+
+var Scopes = []string{ScopeMoodRead, ScopeMoodWrite, ScopeAdviceRead, ScopeQuoteRead}
+
+// GeneratePersonalAccessToken returns a new prefixed token together with its hash
+func GeneratePersonalAccessToken() (string, string, error) {
+	plain, _, err := GenerateRandomToken()
+	if err != nil {
+		return "", "", err
+	}
+
+	plain = PersonalAccessTokenPrefix + plain
+	return plain, HashRandomToken(plain), nil
+}
+
+// IsPersonalAccessToken reports whether the bearer token is a personal access token
+func IsPersonalAccessToken(tokenString string) bool {
+	return strings.HasPrefix(tokenString, PersonalAccessTokenPrefix)
+}
+
+// IsValidScope reports whether scope can be granted to a personal access token
+func IsValidScope(scope string) bool {
+	return slices.Contains(Scopes, scope)
+}
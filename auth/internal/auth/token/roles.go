@@ -0,0 +1,20 @@
+package token
+
+import "slices"
+
+// Roles that can be assigned to users
+const (
+	RoleAdmin = "admin"
+)
+
+var Roles = []string{RoleAdmin}
+
+// IsValidRole reports whether role can be assigned to a user
+func IsValidRole(role string) bool {
+	return slices.Contains(Roles, role)
+}
+
+// HasRole reports whether the claims grant the given role
+func (c *UserClaims) HasRole(role string) bool {
+	return slices.Contains(c.Roles, role)
+}
@@ -1,43 +1,107 @@
 package token
 
 import (
+	"errors"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// PurposeTwoFactor marks challenge tokens issued after the password step of a two-factor login
+const PurposeTwoFactor = "2fa"
+
 type UserClaims struct {
 	UserID int
+	// SessionID identifies the login the access token belongs to
+	SessionID string `json:"sid,omitempty"`
+	// Roles are fixed when the token is issued, role changes apply from the next refresh
+	Roles []string `json:"roles,omitempty"`
+	// Timezone and WeekStart come from the user's preferences and, like roles, change from the next refresh
+	Timezone  string `json:"tz,omitempty"`
+	WeekStart string `json:"wks,omitempty"`
+	// Purpose is empty for access tokens and set for single-step tokens that must not authorize requests
+	Purpose string `json:"purpose,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func GenerateJWT(userID int, key string) (string, error) {
+func GenerateJWT(userID int, sessionID string, roles []string, timezone, weekStart string, keys *KeySet) (string, *UserClaims, error) {
+	jti, err := GenerateID()
+	if err != nil {
+		return "", nil, err
+	}
+
 	claims := UserClaims{
-		UserID: userID,
+		UserID:    userID,
+		SessionID: sessionID,
+		Roles:     roles,
+		Timezone:  timezone,
+		WeekStart: weekStart,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(2 * time.Hour)),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	signedJWT, err := token.SignedString([]byte(key))
+	signedJWT, err := keys.sign(claims)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return signedJWT, &claims, nil
+}
+
+func ValidateJWT(tokenString string, keys *KeySet) (*UserClaims, error) {
+	claims, err := parseJWT(tokenString, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.Purpose != "" {
+		return nil, errors.New("not an access token")
+	}
+
+	return claims, nil
+}
+
+// GenerateChallengeJWT creates a short-lived token that can only be used for the given purpose
+func GenerateChallengeJWT(userID int, purpose string, ttl time.Duration, keys *KeySet) (string, error) {
+	jti, err := GenerateID()
 	if err != nil {
 		return "", err
 	}
 
-	return signedJWT, nil
+	claims := UserClaims{
+		UserID:  userID,
+		Purpose: purpose,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+		},
+	}
+
+	return keys.sign(claims)
+}
+
+// ValidateChallengeJWT validates a token created by GenerateChallengeJWT for the given purpose
+func ValidateChallengeJWT(tokenString, purpose string, keys *KeySet) (*UserClaims, error) {
+	claims, err := parseJWT(tokenString, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.Purpose != purpose {
+		return nil, errors.New("wrong token purpose")
+	}
+
+	return claims, nil
 }
 
-func ValidateJWT(tokenString string, key string) (*UserClaims, error) {
+func parseJWT(tokenString string, keys *KeySet) (*UserClaims, error) {
 	claims := &UserClaims{}
 
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, jwt.ErrSignatureInvalid
-		}
-		return []byte(key), nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, claims, keys.keyFunc, jwt.WithValidMethods([]string{"RS256", "EdDSA"}))
 	if err != nil {
 		return nil, err
 	}
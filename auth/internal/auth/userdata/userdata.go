@@ -0,0 +1,59 @@
+package userdata
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ciameksw/mood-api/auth/internal/auth/config"
+)
+
+// Client erases the data other services keep about a user
+type Client struct {
+	MoodURL   string
+	AdviceURL string
+
+	client *http.Client
+}
+
+func NewClient(cfg *config.Config) *Client {
+	return &Client{
+		MoodURL:   cfg.MoodURL,
+		AdviceURL: cfg.AdviceURL,
+		client:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// DeleteUserData deletes the user's mood entries and advice history, it is safe to repeat after a partial failure
+func (c *Client) DeleteUserData(ctx context.Context, userID int) error {
+	id := strconv.Itoa(userID)
+
+	if err := c.delete(ctx, c.MoodURL+"/mood/user/"+id); err != nil {
+		return fmt.Errorf("mood service: %w", err)
+	}
+	if err := c.delete(ctx, c.AdviceURL+"/advice/user/"+id); err != nil {
+		return fmt.Errorf("advice service: %w", err)
+	}
+
+	return nil
+}
+
+func (c *Client) delete(ctx context.Context, url string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
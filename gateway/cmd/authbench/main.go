@@ -0,0 +1,205 @@
+// Command authbench measures the latency the gateway adds to a protected request,
+// comparing remote authorization through the auth service with local token verification.
+//
+//	go run ./cmd/authbench -requests 2000 -auth-delay 2ms
+//
+// The auth service and the downstream service are in-process fakes, so the numbers
+// isolate the cost of the gateway itself plus the simulated auth service latency.
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ciameksw/mood-api/gateway/internal/gateway/config"
+	"github.com/ciameksw/mood-api/gateway/internal/gateway/server"
+	"github.com/ciameksw/mood-api/pkg/jwks"
+	"github.com/ciameksw/mood-api/pkg/logger"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const benchKID = "bench"
+
+func main() {
+	requests := flag.Int("requests", 2000, "number of requests per mode")
+	authDelay := flag.Duration("auth-delay", 2*time.Millisecond, "simulated processing time of the auth service per authorization")
+	flag.Parse()
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		log.Fatalf("Failed to generate key: %v", err)
+	}
+
+	authSrv := httptest.NewServer(fakeAuthService(pub, *authDelay))
+	defer authSrv.Close()
+
+	quoteSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"quote":"Benchmarks never lie","author":"Nobody"}`))
+	}))
+	defer quoteSrv.Close()
+
+	token, err := signToken(priv)
+	if err != nil {
+		log.Fatalf("Failed to sign token: %v", err)
+	}
+
+	fmt.Printf("%d requests per mode, simulated auth service delay %v\n\n", *requests, *authDelay)
+	fmt.Printf("%-8s %10s %10s %10s %10s\n", "mode", "mean", "p50", "p90", "p99")
+
+	for _, local := range []bool{false, true} {
+		latencies, err := run(local, authSrv.URL, quoteSrv.URL, token, *requests)
+		if err != nil {
+			log.Fatalf("Benchmark failed: %v", err)
+		}
+
+		mode := "remote"
+		if local {
+			mode = "local"
+		}
+		fmt.Printf("%-8s %10v %10v %10v %10v\n", mode, mean(latencies), percentile(latencies, 50), percentile(latencies, 90), percentile(latencies, 99))
+	}
+}
+
+func run(local bool, authURL, quoteURL, token string, requests int) ([]time.Duration, error) {
+	cfg := &config.Config{
+		AuthURL:                    authURL,
+		QuoteURL:                   quoteURL,
+		AuthLocalVerification:      local,
+		AuthKeysRefreshInterval:    time.Minute,
+		AuthRevocationSyncInterval: time.Second,
+	}
+	quiet := &logger.Logger{
+		Info:  log.New(io.Discard, "", 0),
+		Error: log.New(os.Stderr, "ERROR: ", log.Ltime),
+	}
+
+	s := server.NewServer(quiet, cfg)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s.StartBackground(ctx)
+
+	gw := httptest.NewServer(s.Handler())
+	defer gw.Close()
+
+	client := gw.Client()
+	send := func() (time.Duration, error) {
+		req, err := http.NewRequest(http.MethodGet, gw.URL+"/quote/today", nil)
+		if err != nil {
+			return 0, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		start := time.Now()
+		resp, err := client.Do(req)
+		if err != nil {
+			return 0, err
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		elapsed := time.Since(start)
+
+		if resp.StatusCode != http.StatusOK {
+			return 0, fmt.Errorf("unexpected status %s", resp.Status)
+		}
+		return elapsed, nil
+	}
+
+	// Warm up connections and, in local mode, wait for the first key and revocation sync
+	deadline := time.Now().Add(5 * time.Second)
+	for i := 0; i < 50 || (local && !localReady(s, token)); i++ {
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("gateway did not become ready")
+		}
+		if _, err := send(); err != nil {
+			return nil, err
+		}
+	}
+
+	latencies := make([]time.Duration, 0, requests)
+	for i := 0; i < requests; i++ {
+		d, err := send()
+		if err != nil {
+			return nil, err
+		}
+		latencies = append(latencies, d)
+	}
+	return latencies, nil
+}
+
+func localReady(s *server.Server, token string) bool {
+	_, err := s.TokenVerifier.Verify(token)
+	return err == nil
+}
+
+func fakeAuthService(pub ed25519.PublicKey, delay time.Duration) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /auth/.well-known/jwks.json", func(w http.ResponseWriter, r *http.Request) {
+		key, _ := jwks.FromPublicKey(benchKID, pub)
+		writeJSON(w, jwks.Set{Keys: []jwks.Key{key}})
+	})
+
+	mux.HandleFunc("GET /auth/revocations", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]interface{}{"asOf": time.Now(), "revoked": []interface{}{}})
+	})
+
+	mux.HandleFunc("GET /auth/authorize", func(w http.ResponseWriter, r *http.Request) {
+		// Stands in for the signature check and revocation lookup done by the real service
+		time.Sleep(delay)
+
+		claims := &jwt.RegisteredClaims{}
+		tokenString := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		_, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) { return pub, nil })
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		writeJSON(w, map[string]int{"userId": 1})
+	})
+
+	return mux
+}
+
+func signToken(priv ed25519.PrivateKey) (string, error) {
+	claims := jwt.MapClaims{
+		"UserID": 1,
+		"jti":    "bench-token",
+		"iat":    time.Now().Unix(),
+		"exp":    time.Now().Add(time.Hour).Unix(),
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+	token.Header["kid"] = benchKID
+	return token.SignedString(priv)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func mean(latencies []time.Duration) time.Duration {
+	var total time.Duration
+	for _, d := range latencies {
+		total += d
+	}
+	return (total / time.Duration(len(latencies))).Round(time.Microsecond)
+}
+
+func percentile(latencies []time.Duration, p int) time.Duration {
+	sorted := append([]time.Duration(nil), latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[(len(sorted)-1)*p/100].Round(time.Microsecond)
+}
@@ -21,6 +21,10 @@ func main() {
 
 	s := server.NewServer(lgr, cfg)
 
+	// Start background workers, stopped on shutdown
+	bgCtx, stopBackground := context.WithCancel(context.Background())
+	s.StartBackground(bgCtx)
+
 	// Start server in a goroutine
 	go func() {
 		lgr.Info.Printf("Starting server on %s:%s", cfg.ServerHost, cfg.ServerPort)
@@ -35,6 +39,7 @@ func main() {
 	<-quit
 
 	lgr.Info.Println("Shutting down server...")
+	stopBackground()
 
 	// Create shutdown context with timeout
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -1,3 +1,5 @@
 module github.com/ciameksw/mood-api/gateway
 
 go 1.25.0
+
+require github.com/golang-jwt/jwt/v5 v5.3.0
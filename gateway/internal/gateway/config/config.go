@@ -1,6 +1,12 @@
 package config
 
-import "github.com/ciameksw/mood-api/pkg/configutil"
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ciameksw/mood-api/pkg/configutil"
+)
 
 type Config struct {
 	ServerHost string
@@ -9,6 +15,15 @@ type Config struct {
 	MoodURL    string
 	AuthURL    string
 	QuoteURL   string
+
+	AuthLocalVerification      bool
+	AuthKeysRefreshInterval    time.Duration
+	AuthRevocationSyncInterval time.Duration
+
+	TrustProxyHeaders bool
+
+	ExportDir string
+	ExportTTL time.Duration
 }
 
 func GetConfig() *Config {
@@ -19,5 +34,14 @@ func GetConfig() *Config {
 		MoodURL:    configutil.GetEnv("MOOD_URL", "http://localhost:3002"),
 		AuthURL:    configutil.GetEnv("AUTH_URL", "http://localhost:3001"),
 		QuoteURL:   configutil.GetEnv("QUOTE_URL", "http://localhost:3004"),
+
+		AuthLocalVerification:      configutil.GetEnvBool("AUTH_LOCAL_VERIFICATION", true),
+		AuthKeysRefreshInterval:    configutil.GetEnvDuration("AUTH_KEYS_REFRESH_INTERVAL", 5*time.Minute),
+		AuthRevocationSyncInterval: configutil.GetEnvDuration("AUTH_REVOCATION_SYNC_INTERVAL", 5*time.Second),
+
+		TrustProxyHeaders: configutil.GetEnvBool("TRUST_PROXY_HEADERS", false),
+
+		ExportDir: configutil.GetEnv("EXPORT_DIR", filepath.Join(os.TempDir(), "mood-api-exports")),
+		ExportTTL: configutil.GetEnvDuration("EXPORT_TTL", 24*time.Hour),
 	}
 }
@@ -0,0 +1,173 @@
+package export
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Archive writes the files of an export into a ZIP, records are streamed so histories of any size fit
+type Archive struct {
+	zw       *zip.Writer
+	spoolDir string
+}
+
+// NewArchive starts a ZIP on w, spoolDir holds temporary files while records are written
+func NewArchive(w io.Writer, spoolDir string) *Archive {
+	return &Archive{
+		zw:       zip.NewWriter(w),
+		spoolDir: spoolDir,
+	}
+}
+
+// AddJSON adds name holding v as indented JSON
+func (a *Archive) AddJSON(name string, v interface{}) error {
+	f, err := a.create(name)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// AddRecords reads NDJSON objects from r and adds them as base.json, an array, and base.csv with the given columns
+func (a *Archive) AddRecords(base string, r io.Reader, columns []string) (int, error) {
+	// Only one ZIP entry can be written at a time, so the CSV goes to a temporary file until the JSON is done
+	spool, err := os.CreateTemp(a.spoolDir, "records-*.part")
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		spool.Close()
+		os.Remove(spool.Name())
+	}()
+
+	buffered := bufio.NewWriter(spool)
+	cw := csv.NewWriter(buffered)
+	if err := cw.Write(columns); err != nil {
+		return 0, err
+	}
+
+	f, err := a.create(base + ".json")
+	if err != nil {
+		return 0, err
+	}
+	if _, err := io.WriteString(f, "["); err != nil {
+		return 0, err
+	}
+
+	dec := json.NewDecoder(r)
+	count := 0
+	for {
+		var raw json.RawMessage
+		err := dec.Decode(&raw)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, fmt.Errorf("read record %d: %w", count+1, err)
+		}
+
+		sep := ",\n  "
+		if count == 0 {
+			sep = "\n  "
+		}
+		if _, err := io.WriteString(f, sep); err != nil {
+			return 0, err
+		}
+		if _, err := f.Write(raw); err != nil {
+			return 0, err
+		}
+
+		row, err := csvRow(raw, columns)
+		if err != nil {
+			return 0, fmt.Errorf("read record %d: %w", count+1, err)
+		}
+		if err := cw.Write(row); err != nil {
+			return 0, err
+		}
+		count++
+	}
+
+	closing := "\n]\n"
+	if count == 0 {
+		closing = "]\n"
+	}
+	if _, err := io.WriteString(f, closing); err != nil {
+		return 0, err
+	}
+
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return 0, err
+	}
+	if err := buffered.Flush(); err != nil {
+		return 0, err
+	}
+	if _, err := spool.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	f, err = a.create(base + ".csv")
+	if err != nil {
+		return 0, err
+	}
+	if _, err := io.Copy(f, spool); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// Close writes the ZIP directory, the archive is not valid before
+func (a *Archive) Close() error {
+	return a.zw.Close()
+}
+
+func (a *Archive) create(name string) (io.Writer, error) {
+	return a.zw.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: time.Now(),
+	})
+}
+
+// Helper function to pick the columns of a CSV row out of a JSON object
+func csvRow(raw json.RawMessage, columns []string) ([]string, error) {
+	var record map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if err := dec.Decode(&record); err != nil {
+		return nil, err
+	}
+
+	row := make([]string, len(columns))
+	for i, column := range columns {
+		switch v := record[column].(type) {
+		case nil:
+			row[i] = ""
+		case string:
+			row[i] = v
+		case json.Number:
+			row[i] = v.String()
+		case bool:
+			row[i] = strconv.FormatBool(v)
+		default:
+			b, err := json.Marshal(v)
+			if err != nil {
+				return nil, err
+			}
+			row[i] = string(b)
+		}
+	}
+	return row, nil
+}
@@ -0,0 +1,265 @@
+package export
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ciameksw/mood-api/gateway/internal/gateway/config"
+	"github.com/ciameksw/mood-api/pkg/logger"
+)
+
+type Status string
+
+const (
+	StatusPending   Status = "pending"
+	StatusRunning   Status = "running"
+	StatusCompleted Status = "completed"
+	StatusFailed    Status = "failed"
+)
+
+// ErrInProgress means the user already has an export that has not finished
+var ErrInProgress = errors.New("export already in progress")
+
+// How often finished exports are checked for expiry
+const cleanupInterval = time.Minute
+
+// Job is an export of one user's data, the archive is kept on disk until ExpiresAt
+type Job struct {
+	ID          string     `json:"id"`
+	UserID      int        `json:"-"`
+	Status      Status     `json:"status"`
+	Error       string     `json:"error,omitempty"`
+	Size        int64      `json:"size,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+	ExpiresAt   *time.Time `json:"expiresAt,omitempty"`
+}
+
+// Store keeps export jobs in memory and their archives in Dir, so jobs are lost when the gateway restarts
+type Store struct {
+	Dir    string
+	TTL    time.Duration
+	Logger *logger.Logger
+
+	mu   sync.Mutex
+	jobs map[string]*Job
+}
+
+func NewStore(cfg *config.Config, log *logger.Logger) *Store {
+	return &Store{
+		Dir:    cfg.ExportDir,
+		TTL:    cfg.ExportTTL,
+		Logger: log,
+		jobs:   make(map[string]*Job),
+	}
+}
+
+// Create registers a pending export for the user, only one export per user may be unfinished
+func (st *Store) Create(userID int) (Job, error) {
+	id, err := newJobID()
+	if err != nil {
+		return Job{}, err
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	for _, job := range st.jobs {
+		if job.UserID == userID && (job.Status == StatusPending || job.Status == StatusRunning) {
+			return Job{}, ErrInProgress
+		}
+	}
+
+	job := &Job{
+		ID:        id,
+		UserID:    userID,
+		Status:    StatusPending,
+		CreatedAt: time.Now(),
+	}
+	st.jobs[id] = job
+	return *job, nil
+}
+
+// Get returns a copy of the job with the given id
+func (st *Store) Get(id string) (Job, bool) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	job, ok := st.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return *job, true
+}
+
+// Run writes the archive of a pending job with build and records the outcome, it blocks until build returns
+func (st *Store) Run(id string, build func(w io.Writer) error) {
+	st.update(id, func(job *Job) { job.Status = StatusRunning })
+
+	size, err := st.write(id, build)
+	now := time.Now()
+	expiresAt := now.Add(st.TTL)
+	if err != nil {
+		st.Logger.Error.Printf("Export %s failed: %v", id, err)
+		st.update(id, func(job *Job) {
+			job.Status = StatusFailed
+			job.Error = "The export could not be completed, please try again later"
+			job.CompletedAt = &now
+			job.ExpiresAt = &expiresAt
+		})
+		return
+	}
+
+	st.Logger.Info.Printf("Export %s completed, %d bytes", id, size)
+	st.update(id, func(job *Job) {
+		job.Status = StatusCompleted
+		job.Size = size
+		job.CompletedAt = &now
+		job.ExpiresAt = &expiresAt
+	})
+}
+
+// Open opens the archive of a completed job
+func (st *Store) Open(job Job) (*os.File, error) {
+	if job.Status != StatusCompleted {
+		return nil, errors.New("export is not completed")
+	}
+	return os.Open(st.archivePath(job.ID))
+}
+
+// Start removes expired exports in the background until ctx is cancelled
+func (st *Store) Start(ctx context.Context) {
+	// Archives left by a previous run have no job anymore and could never be downloaded
+	st.removeOrphans()
+
+	go func() {
+		ticker := time.NewTicker(cleanupInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				st.removeExpired()
+			}
+		}
+	}()
+}
+
+// Helper function to write an archive next to its final path and move it there once complete
+func (st *Store) write(id string, build func(w io.Writer) error) (int64, error) {
+	if err := os.MkdirAll(st.Dir, 0o700); err != nil {
+		return 0, err
+	}
+
+	tmp, err := os.CreateTemp(st.Dir, id+"-*.part")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := build(tmp); err != nil {
+		tmp.Close()
+		return 0, err
+	}
+
+	info, err := tmp.Stat()
+	if err != nil {
+		tmp.Close()
+		return 0, err
+	}
+	if err := tmp.Close(); err != nil {
+		return 0, err
+	}
+
+	if err := os.Rename(tmp.Name(), st.archivePath(id)); err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+func (st *Store) update(id string, fn func(job *Job)) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if job, ok := st.jobs[id]; ok {
+		fn(job)
+	}
+}
+
+func (st *Store) removeExpired() {
+	now := time.Now()
+	expired := make([]string, 0)
+
+	st.mu.Lock()
+	for id, job := range st.jobs {
+		if job.ExpiresAt != nil && now.After(*job.ExpiresAt) {
+			delete(st.jobs, id)
+			expired = append(expired, id)
+		}
+	}
+	st.mu.Unlock()
+
+	for _, id := range expired {
+		if err := os.Remove(st.archivePath(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			st.Logger.Error.Printf("Failed to remove expired export %s: %v", id, err)
+		}
+	}
+	if len(expired) > 0 {
+		st.Logger.Info.Printf("Removed %d expired exports", len(expired))
+	}
+}
+
+func (st *Store) removeOrphans() {
+	entries, err := os.ReadDir(st.Dir)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			st.Logger.Error.Printf("Failed to read export directory: %v", err)
+		}
+		return
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !isExportFile(name) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(st.Dir, name)); err != nil {
+			st.Logger.Error.Printf("Failed to remove leftover export %s: %v", name, err)
+		}
+	}
+}
+
+func (st *Store) archivePath(id string) string {
+	return filepath.Join(st.Dir, id+".zip")
+}
+
+// Helper function to recognize archives and temporary files written by the store
+func isExportFile(name string) bool {
+	if strings.HasSuffix(name, ".part") {
+		return true
+	}
+	id, ok := strings.CutSuffix(name, ".zip")
+	if !ok || len(id) != 32 {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
+
+func newJobID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
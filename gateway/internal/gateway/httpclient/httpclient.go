@@ -11,6 +11,7 @@ type RequestParams struct {
 	Body          io.Reader
 	ContentType   *string
 	Authorization *string
+	Headers       map[string]string
 }
 
 func SendRequest(params RequestParams) (*http.Response, error) {
@@ -25,8 +26,16 @@ func SendRequest(params RequestParams) (*http.Response, error) {
 	if params.Authorization != nil {
 		req.Header.Set("Authorization", *params.Authorization)
 	}
+	for key, value := range params.Headers {
+		req.Header.Set(key, value)
+	}
 
-	client := &http.Client{}
+	// Redirects from the services are meant for the client, so they are passed back instead of followed
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
 	resp, err := client.Do(req)
 	if err != nil {
 		return nil, err
@@ -20,6 +20,15 @@ func (s *Server) forwardResponse(w http.ResponseWriter, resp *http.Response) {
 	defer resp.Body.Close()
 
 	w.Header().Set("Content-Type", resp.Header.Get("Content-Type"))
+	if retryAfter := resp.Header.Get("Retry-After"); retryAfter != "" {
+		w.Header().Set("Retry-After", retryAfter)
+	}
+	if location := resp.Header.Get("Location"); location != "" {
+		w.Header().Set("Location", location)
+	}
+	for _, cookie := range resp.Header.Values("Set-Cookie") {
+		w.Header().Add("Set-Cookie", cookie)
+	}
 	w.WriteHeader(resp.StatusCode)
 	io.Copy(w, resp.Body)
 }
@@ -10,9 +10,15 @@ import (
 )
 
 type selectAdviceInputEntry struct {
-	MoodTypeID int     `json:"moodTypeId" validate:"required"`
-	Count      int     `json:"count" validate:"required,min=1"`
-	Percentage float64 `json:"percentage" validate:"required"`
+	MoodTypeID         int     `json:"moodTypeId" validate:"required"`
+	Count              int     `json:"count" validate:"required,min=1"`
+	Percentage         float64 `json:"percentage" validate:"required"`
+	WeightedPercentage float64 `json:"weightedPercentage"`
+}
+
+// moodSummary is the part of the mood summary the advice is selected from
+type moodSummary struct {
+	Moods []selectAdviceInputEntry `json:"moods"`
 }
 
 func (s *Server) handleGetAdvice(w http.ResponseWriter, r *http.Request) {
@@ -43,7 +49,8 @@ func (s *Server) handleGetAdvice(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	resp, err = s.MoodService.GetSummary(from, to, userID)
+	// Advice is chosen from the summary with the mood service's default rollup
+	resp, err = s.MoodService.GetSummary(from, to, userID, "", false)
 	if err != nil {
 		httputil.HandleError(*s.Logger, w, "Failed to send request to mood service", err, http.StatusInternalServerError)
 		return
@@ -61,11 +68,12 @@ func (s *Server) handleGetAdvice(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	entries := make([]selectAdviceInputEntry, 0)
-	if err := json.Unmarshal(body, &entries); err != nil {
+	var summary moodSummary
+	if err := json.Unmarshal(body, &summary); err != nil {
 		httputil.HandleError(*s.Logger, w, "Failed to parse mood summary", err, http.StatusInternalServerError)
 		return
 	}
+	entries := summary.Moods
 
 	// Validate entries according to tags
 	for _, e := range entries {
@@ -131,3 +139,63 @@ func (s *Server) handleGetAdvice(w http.ResponseWriter, r *http.Request) {
 
 	httputil.WriteData(*s.Logger, w, adviceResp, http.StatusOK)
 }
+
+func (s *Server) handleGetAdviceTypes(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Admin get advice types")
+
+	resp, err := s.AdviceService.GetTypes(r)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to send request to advice service", err, http.StatusInternalServerError)
+		return
+	}
+
+	s.forwardResponse(w, resp)
+}
+
+func (s *Server) handleGetAdviceCatalog(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Admin get advice catalog")
+
+	resp, err := s.AdviceService.GetCatalog(r)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to send request to advice service", err, http.StatusInternalServerError)
+		return
+	}
+
+	s.forwardResponse(w, resp)
+}
+
+func (s *Server) handleAddCatalogAdvice(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Admin add advice")
+
+	resp, err := s.AdviceService.AddCatalogAdvice(r)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to send request to advice service", err, http.StatusInternalServerError)
+		return
+	}
+
+	s.forwardResponse(w, resp)
+}
+
+func (s *Server) handleUpdateCatalogAdvice(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Admin update advice")
+
+	resp, err := s.AdviceService.UpdateCatalogAdvice(r)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to send request to advice service", err, http.StatusInternalServerError)
+		return
+	}
+
+	s.forwardResponse(w, resp)
+}
+
+func (s *Server) handleDeleteCatalogAdvice(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Admin delete advice")
+
+	resp, err := s.AdviceService.DeleteCatalogAdvice(r)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to send request to advice service", err, http.StatusInternalServerError)
+		return
+	}
+
+	s.forwardResponse(w, resp)
+}
@@ -30,6 +30,354 @@ func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
 	s.forwardResponse(w, resp)
 }
 
+func (s *Server) handleRefresh(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Refresh token")
+
+	resp, err := s.AuthService.Refresh(r)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to send request to auth service", err, http.StatusInternalServerError)
+		return
+	}
+
+	s.forwardResponse(w, resp)
+}
+
+func (s *Server) handleForgotPassword(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Forgot password")
+
+	resp, err := s.AuthService.ForgotPassword(r)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to send request to auth service", err, http.StatusInternalServerError)
+		return
+	}
+
+	s.forwardResponse(w, resp)
+}
+
+func (s *Server) handleResetPassword(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Reset password")
+
+	resp, err := s.AuthService.ResetPassword(r)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to send request to auth service", err, http.StatusInternalServerError)
+		return
+	}
+
+	s.forwardResponse(w, resp)
+}
+
+func (s *Server) handleChangePassword(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Change password")
+
+	resp, err := s.AuthService.ChangePassword(r)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to send request to auth service", err, http.StatusInternalServerError)
+		return
+	}
+
+	s.forwardResponse(w, resp)
+}
+
+func (s *Server) handleRequestMagicLink(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Request login link")
+
+	resp, err := s.AuthService.RequestMagicLink(r)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to send request to auth service", err, http.StatusInternalServerError)
+		return
+	}
+
+	s.forwardResponse(w, resp)
+}
+
+func (s *Server) handleConsumeMagicLink(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Consume login link")
+
+	resp, err := s.AuthService.ConsumeMagicLink(r)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to send request to auth service", err, http.StatusInternalServerError)
+		return
+	}
+
+	s.forwardResponse(w, resp)
+}
+
+func (s *Server) handleVerifyEmail(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Verify email")
+
+	resp, err := s.AuthService.VerifyEmail(r)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to send request to auth service", err, http.StatusInternalServerError)
+		return
+	}
+
+	s.forwardResponse(w, resp)
+}
+
+func (s *Server) handleResendVerification(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Resend verification email")
+
+	resp, err := s.AuthService.ResendVerification(r)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to send request to auth service", err, http.StatusInternalServerError)
+		return
+	}
+
+	s.forwardResponse(w, resp)
+}
+
+func (s *Server) handleTwoFactorVerify(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Verify two-factor login")
+
+	resp, err := s.AuthService.TwoFactorVerify(r)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to send request to auth service", err, http.StatusInternalServerError)
+		return
+	}
+
+	s.forwardResponse(w, resp)
+}
+
+func (s *Server) handleTwoFactorSetup(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Start two-factor setup")
+
+	resp, err := s.AuthService.TwoFactorSetup(r)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to send request to auth service", err, http.StatusInternalServerError)
+		return
+	}
+
+	s.forwardResponse(w, resp)
+}
+
+func (s *Server) handleTwoFactorConfirm(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Confirm two-factor setup")
+
+	resp, err := s.AuthService.TwoFactorConfirm(r)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to send request to auth service", err, http.StatusInternalServerError)
+		return
+	}
+
+	s.forwardResponse(w, resp)
+}
+
+func (s *Server) handleRegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Regenerate recovery codes")
+
+	resp, err := s.AuthService.RegenerateRecoveryCodes(r)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to send request to auth service", err, http.StatusInternalServerError)
+		return
+	}
+
+	s.forwardResponse(w, resp)
+}
+
+func (s *Server) handleTwoFactorDisable(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Disable two-factor authentication")
+
+	resp, err := s.AuthService.TwoFactorDisable(r)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to send request to auth service", err, http.StatusInternalServerError)
+		return
+	}
+
+	s.forwardResponse(w, resp)
+}
+
+func (s *Server) handleJWKS(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Get JWKS")
+
+	resp, err := s.AuthService.GetJWKS(r)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to send request to auth service", err, http.StatusInternalServerError)
+		return
+	}
+
+	s.forwardResponse(w, resp)
+}
+
+func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Logout user")
+
+	resp, err := s.AuthService.Logout(r)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to send request to auth service", err, http.StatusInternalServerError)
+		return
+	}
+
+	s.forwardResponse(w, resp)
+}
+
+func (s *Server) handleLogoutAll(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Logout user from all sessions")
+
+	resp, err := s.AuthService.LogoutAll(r)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to send request to auth service", err, http.StatusInternalServerError)
+		return
+	}
+
+	s.forwardResponse(w, resp)
+}
+
+func (s *Server) handleCreatePersonalToken(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Create personal access token")
+
+	resp, err := s.AuthService.CreatePersonalToken(r)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to send request to auth service", err, http.StatusInternalServerError)
+		return
+	}
+
+	s.forwardResponse(w, resp)
+}
+
+func (s *Server) handleGetPersonalTokens(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Get personal access tokens")
+
+	resp, err := s.AuthService.GetPersonalTokens(r)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to send request to auth service", err, http.StatusInternalServerError)
+		return
+	}
+
+	s.forwardResponse(w, resp)
+}
+
+func (s *Server) handleDeletePersonalToken(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Delete personal access token")
+
+	resp, err := s.AuthService.DeletePersonalToken(r)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to send request to auth service", err, http.StatusInternalServerError)
+		return
+	}
+
+	s.forwardResponse(w, resp)
+}
+
+func (s *Server) handleGetSessions(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Get sessions")
+
+	resp, err := s.AuthService.GetSessions(r)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to send request to auth service", err, http.StatusInternalServerError)
+		return
+	}
+
+	s.forwardResponse(w, resp)
+}
+
+func (s *Server) handleDeleteSession(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Delete session")
+
+	resp, err := s.AuthService.DeleteSession(r)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to send request to auth service", err, http.StatusInternalServerError)
+		return
+	}
+
+	s.forwardResponse(w, resp)
+}
+
+func (s *Server) handleGetOIDCProviders(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Get identity providers")
+
+	resp, err := s.AuthService.GetOIDCProviders(r)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to send request to auth service", err, http.StatusInternalServerError)
+		return
+	}
+
+	s.forwardResponse(w, resp)
+}
+
+func (s *Server) handleOIDCLogin(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Identity provider login")
+
+	resp, err := s.AuthService.OIDCLogin(r)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to send request to auth service", err, http.StatusInternalServerError)
+		return
+	}
+
+	s.forwardResponse(w, resp)
+}
+
+func (s *Server) handleOIDCCallback(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Identity provider callback")
+
+	resp, err := s.AuthService.OIDCCallback(r)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to send request to auth service", err, http.StatusInternalServerError)
+		return
+	}
+
+	s.forwardResponse(w, resp)
+}
+
+func (s *Server) handleAdminGetUsers(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Admin get users")
+
+	resp, err := s.AuthService.GetUsers(r)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to send request to auth service", err, http.StatusInternalServerError)
+		return
+	}
+
+	s.forwardResponse(w, resp)
+}
+
+func (s *Server) handleAdminSetUserRoles(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Admin set user roles")
+
+	resp, err := s.AuthService.SetUserRoles(r)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to send request to auth service", err, http.StatusInternalServerError)
+		return
+	}
+
+	s.forwardResponse(w, resp)
+}
+
+func (s *Server) handleAdminRestoreUser(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Admin restore user")
+
+	resp, err := s.AuthService.AdminRestoreUser(r)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to send request to auth service", err, http.StatusInternalServerError)
+		return
+	}
+
+	s.forwardResponse(w, resp)
+}
+
+func (s *Server) handleAdminGetEvents(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Admin get auth events")
+
+	resp, err := s.AuthService.AdminGetEvents(r)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to send request to auth service", err, http.StatusInternalServerError)
+		return
+	}
+
+	s.forwardResponse(w, resp)
+}
+
+func (s *Server) handleGetEvents(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Get account activity")
+
+	resp, err := s.AuthService.GetEvents(r)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to send request to auth service", err, http.StatusInternalServerError)
+		return
+	}
+
+	s.forwardResponse(w, resp)
+}
+
 func (s *Server) handleGetUser(w http.ResponseWriter, r *http.Request) {
 	s.Logger.Info.Println("Get logged user")
 
@@ -54,6 +402,30 @@ func (s *Server) handleUpdateUser(w http.ResponseWriter, r *http.Request) {
 	s.forwardResponse(w, resp)
 }
 
+func (s *Server) handleGetPreferences(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Get user preferences")
+
+	resp, err := s.AuthService.GetPreferences(r)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to send request to auth service", err, http.StatusInternalServerError)
+		return
+	}
+
+	s.forwardResponse(w, resp)
+}
+
+func (s *Server) handleUpdatePreferences(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Update user preferences")
+
+	resp, err := s.AuthService.UpdatePreferences(r)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to send request to auth service", err, http.StatusInternalServerError)
+		return
+	}
+
+	s.forwardResponse(w, resp)
+}
+
 func (s *Server) handleDeleteUser(w http.ResponseWriter, r *http.Request) {
 	s.Logger.Info.Println("Delete logged user")
 
@@ -65,3 +437,15 @@ func (s *Server) handleDeleteUser(w http.ResponseWriter, r *http.Request) {
 
 	s.forwardResponse(w, resp)
 }
+
+func (s *Server) handleRestoreUser(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Restore user")
+
+	resp, err := s.AuthService.RestoreUser(r)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to send request to auth service", err, http.StatusInternalServerError)
+		return
+	}
+
+	s.forwardResponse(w, resp)
+}
@@ -0,0 +1,153 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/ciameksw/mood-api/gateway/internal/gateway/export"
+	"github.com/ciameksw/mood-api/pkg/httputil"
+)
+
+// Columns of the CSV files, named after the JSON fields streamed by the services
+var (
+	moodExportColumns   = []string{"id", "moodDate", "loggedAt", "timezone", "moodTypeId", "moodType", "intensity", "note", "tags", "createdAt"}
+	adviceExportColumns = []string{"id", "periodFrom", "periodTo", "adviceId", "adviceType", "title", "content", "createdAt"}
+)
+
+func (s *Server) handleCreateExport(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Starting data export")
+
+	userID, ok := getUserIDFromContext(r.Context())
+	if !ok {
+		httputil.HandleError(*s.Logger, w, "Failed to get user ID from context", nil, http.StatusUnauthorized)
+		return
+	}
+
+	job, err := s.Exports.Create(userID)
+	if err != nil {
+		if errors.Is(err, export.ErrInProgress) {
+			httputil.HandleError(*s.Logger, w, "An export is already in progress", nil, http.StatusConflict)
+			return
+		}
+		httputil.HandleError(*s.Logger, w, "Failed to start export", err, http.StatusInternalServerError)
+		return
+	}
+
+	// The profile is read with the caller's token, the services are asked for the user id directly
+	authHeader := r.Header.Get("Authorization")
+	go s.Exports.Run(job.ID, func(w io.Writer) error {
+		return s.buildExport(w, userID, authHeader)
+	})
+
+	s.Logger.Info.Printf("Export %s started for user %d", job.ID, userID)
+	w.Header().Set("Location", "/export/"+job.ID)
+	httputil.WriteData(*s.Logger, w, job, http.StatusAccepted)
+}
+
+func (s *Server) handleGetExport(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Getting data export")
+
+	userID, ok := getUserIDFromContext(r.Context())
+	if !ok {
+		httputil.HandleError(*s.Logger, w, "Failed to get user ID from context", nil, http.StatusUnauthorized)
+		return
+	}
+
+	// Exports of other users are reported as missing so their ids cannot be probed
+	job, ok := s.Exports.Get(r.PathValue("id"))
+	if !ok || job.UserID != userID {
+		httputil.HandleError(*s.Logger, w, "Export not found", nil, http.StatusNotFound)
+		return
+	}
+
+	if job.Status != export.StatusCompleted {
+		httputil.WriteData(*s.Logger, w, job, http.StatusOK)
+		return
+	}
+
+	f, err := s.Exports.Open(job)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to open export", err, http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+
+	filename := fmt.Sprintf("mood-api-export-%s.zip", job.CompletedAt.Format("2006-01-02"))
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	http.ServeContent(w, r, filename, *job.CompletedAt, f)
+}
+
+// Helper function to collect the user's data from every service into the export archive
+func (s *Server) buildExport(w io.Writer, userID int, authHeader string) error {
+	archive := export.NewArchive(w, s.Exports.Dir)
+
+	user, err := readServiceJSON(s.AuthService.GetUserForExport(authHeader))
+	if err != nil {
+		return fmt.Errorf("auth service profile: %w", err)
+	}
+	preferences, err := readServiceJSON(s.AuthService.GetPreferencesForExport(authHeader))
+	if err != nil {
+		return fmt.Errorf("auth service preferences: %w", err)
+	}
+	profile := map[string]json.RawMessage{
+		"user":        user,
+		"preferences": preferences,
+	}
+	if err := archive.AddJSON("profile.json", profile); err != nil {
+		return err
+	}
+
+	resp, err := s.MoodService.ExportUserMoods(userID)
+	if err != nil {
+		return fmt.Errorf("mood service: %w", err)
+	}
+	moods, err := addServiceRecords(archive, "mood_entries", resp, moodExportColumns)
+	if err != nil {
+		return fmt.Errorf("mood service: %w", err)
+	}
+
+	resp, err = s.AdviceService.ExportUserAdvice(userID)
+	if err != nil {
+		return fmt.Errorf("advice service: %w", err)
+	}
+	advice, err := addServiceRecords(archive, "advice", resp, adviceExportColumns)
+	if err != nil {
+		return fmt.Errorf("advice service: %w", err)
+	}
+
+	s.Logger.Info.Printf("Exported profile, %d mood entries and %d advice of user %d", moods, advice, userID)
+	return archive.Close()
+}
+
+// Helper function to read a small JSON response of a service
+func readServiceJSON(resp *http.Response, err error) (json.RawMessage, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	var data json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Helper function to stream an NDJSON response of a service into the archive
+func addServiceRecords(archive *export.Archive, base string, resp *http.Response, columns []string) (int, error) {
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	return archive.AddRecords(base, resp.Body, columns)
+}
@@ -4,17 +4,22 @@ import (
 	"encoding/json"
 	"io"
 	"net/http"
+	"net/url"
 	"strconv"
 	"time"
 
 	"github.com/ciameksw/mood-api/pkg/httputil"
+	"github.com/ciameksw/mood-api/pkg/localtime"
 	"github.com/ciameksw/mood-api/pkg/queryutil"
 )
 
 type addMoodInput struct {
-	MoodTypeID int    `json:"moodTypeId" validate:"required"`
-	Note       string `json:"note" validate:"max=500"`
-	Date       string `json:"date" validate:"required,datetime=2006-01-02"`
+	MoodTypeID int      `json:"moodTypeId" validate:"required"`
+	Intensity  *int     `json:"intensity" validate:"omitnil,min=1,max=10"`
+	Note       string   `json:"note" validate:"max=500"`
+	Tags       []string `json:"tags" validate:"omitempty,max=10,dive,required,max=30"`
+	Date       string   `json:"date" validate:"omitempty,excluded_with=LoggedAt,datetime=2006-01-02"`
+	LoggedAt   string   `json:"loggedAt" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
 }
 
 func (s *Server) handleAddMood(w http.ResponseWriter, r *http.Request) {
@@ -29,7 +34,7 @@ func (s *Server) handleAddMood(w http.ResponseWriter, r *http.Request) {
 
 	err = s.Validator.Struct(input)
 	if err != nil {
-		httputil.HandleError(*s.Logger, w, err.Error(), err, http.StatusBadRequest)
+		httputil.HandleValidationError(*s.Logger, w, err)
 		return
 	}
 
@@ -42,8 +47,11 @@ func (s *Server) handleAddMood(w http.ResponseWriter, r *http.Request) {
 	body := map[string]interface{}{
 		"userId":     userID,
 		"moodTypeId": input.MoodTypeID,
+		"intensity":  input.Intensity,
 		"note":       input.Note,
+		"tags":       input.Tags,
 		"date":       input.Date,
+		"loggedAt":   input.LoggedAt,
 	}
 	bodyBytes, err := json.Marshal(body)
 	if err != nil {
@@ -51,7 +59,7 @@ func (s *Server) handleAddMood(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	resp, err := s.MoodService.Add(bodyBytes)
+	resp, err := s.MoodService.Add(bodyBytes, localtime.Headers(r))
 	if err != nil {
 		httputil.HandleError(*s.Logger, w, "Failed to send request to mood service", err, http.StatusInternalServerError)
 		return
@@ -87,7 +95,16 @@ func (s *Server) handleGetMoodSummary(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	resp, err := s.MoodService.GetSummary(from, to, userID)
+	byTag := false
+	if v := r.URL.Query().Get("byTag"); v != "" {
+		byTag, err = strconv.ParseBool(v)
+		if err != nil {
+			httputil.WriteValidationErrors(*s.Logger, w, []httputil.FieldError{{Field: "byTag", Message: "must be true or false"}})
+			return
+		}
+	}
+
+	resp, err := s.MoodService.GetSummary(from, to, userID, r.URL.Query().Get("rollup"), byTag)
 	if err != nil {
 		httputil.HandleError(*s.Logger, w, "Failed to send request to mood service", err, http.StatusInternalServerError)
 		return
@@ -99,19 +116,68 @@ func (s *Server) handleGetMoodSummary(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleGetMoods(w http.ResponseWriter, r *http.Request) {
 	s.Logger.Info.Println("Getting moods")
 
-	from, to, err := queryutil.ParseTimeframeParams(r)
+	q := r.URL.Query()
+	query := url.Values{
+		"limit":  {q.Get("limit")},
+		"cursor": {q.Get("cursor")},
+		"sort":   {q.Get("sort")},
+		"tag":    q["tag"],
+	}
+
+	// The cursor carries the date range of the first page, the mood service rejects a range given with it
+	if q.Get("cursor") == "" || q.Get("from") != "" || q.Get("to") != "" || q.Get("period") != "" {
+		from, to, err := queryutil.ParseTimeframeParams(r)
+		if err != nil {
+			httputil.HandleError(*s.Logger, w, "Invalid query parameters", err, http.StatusBadRequest)
+			return
+		}
+		query.Set("from", from)
+		query.Set("to", to)
+	}
+
+	userID, ok := getUserIDFromContext(r.Context())
+	if !ok {
+		httputil.HandleError(*s.Logger, w, "Failed to get user ID from context", nil, http.StatusUnauthorized)
+		return
+	}
+
+	resp, err := s.MoodService.GetMoods(userID, query)
 	if err != nil {
-		httputil.HandleError(*s.Logger, w, "Invalid query parameters", err, http.StatusBadRequest)
+		httputil.HandleError(*s.Logger, w, "Failed to send request to mood service", err, http.StatusInternalServerError)
 		return
 	}
 
+	s.forwardResponse(w, resp)
+}
+
+func (s *Server) handleSearchMoods(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Searching moods")
+
+	q := r.URL.Query()
+	search := url.Values{
+		"q":      {q.Get("q")},
+		"limit":  {q.Get("limit")},
+		"offset": {q.Get("offset")},
+	}
+
+	// The date range is optional, a period is resolved here in the user's time zone like for the other mood endpoints
+	if q.Get("from") != "" || q.Get("to") != "" || q.Get("period") != "" {
+		from, to, err := queryutil.ParseTimeframeParams(r)
+		if err != nil {
+			httputil.HandleError(*s.Logger, w, "Invalid query parameters", err, http.StatusBadRequest)
+			return
+		}
+		search.Set("from", from)
+		search.Set("to", to)
+	}
+
 	userID, ok := getUserIDFromContext(r.Context())
 	if !ok {
 		httputil.HandleError(*s.Logger, w, "Failed to get user ID from context", nil, http.StatusUnauthorized)
 		return
 	}
 
-	resp, err := s.MoodService.GetMoods(from, to, userID)
+	resp, err := s.MoodService.Search(userID, search)
 	if err != nil {
 		httputil.HandleError(*s.Logger, w, "Failed to send request to mood service", err, http.StatusInternalServerError)
 		return
@@ -163,8 +229,12 @@ func (s *Server) handleGetMood(w http.ResponseWriter, r *http.Request) {
 		ID         int       `json:"id"`
 		UserID     int       `json:"userId"`
 		MoodDate   string    `json:"moodDate"`
+		LoggedAt   time.Time `json:"loggedAt"`
+		Timezone   string    `json:"timezone"`
 		MoodTypeID int       `json:"moodTypeId"`
+		Intensity  *int      `json:"intensity"`
 		Note       string    `json:"note"`
+		Tags       []string  `json:"tags"`
 		CreatedAt  time.Time `json:"createdAt"`
 	}
 	if err := json.Unmarshal(bodyBytes, &entry); err != nil {
@@ -238,7 +308,10 @@ func (s *Server) handleDeleteMood(w http.ResponseWriter, r *http.Request) {
 type updateMoodInput struct {
 	ID         int    `json:"id" validate:"required"`
 	MoodTypeID int    `json:"moodTypeId" validate:"required"`
+	Intensity  *int   `json:"intensity" validate:"omitnil,min=1,max=10"`
 	Note       string `json:"note" validate:"required,max=500"`
+	// Tags replace the entry's tags, leaving them out keeps the current ones
+	Tags []string `json:"tags" validate:"omitempty,max=10,dive,required,max=30"`
 }
 
 func (s *Server) handleUpdateMood(w http.ResponseWriter, r *http.Request) {
@@ -253,7 +326,7 @@ func (s *Server) handleUpdateMood(w http.ResponseWriter, r *http.Request) {
 
 	err = s.Validator.Struct(input)
 	if err != nil {
-		httputil.HandleError(*s.Logger, w, err.Error(), err, http.StatusBadRequest)
+		httputil.HandleValidationError(*s.Logger, w, err)
 		return
 	}
 
@@ -292,7 +365,9 @@ func (s *Server) handleUpdateMood(w http.ResponseWriter, r *http.Request) {
 	body := map[string]interface{}{
 		"id":         input.ID,
 		"moodTypeId": input.MoodTypeID,
+		"intensity":  input.Intensity,
 		"note":       input.Note,
+		"tags":       input.Tags,
 	}
 	bodyBytes, err := json.Marshal(body)
 	if err != nil {
@@ -307,3 +382,172 @@ func (s *Server) handleUpdateMood(w http.ResponseWriter, r *http.Request) {
 	}
 	s.forwardResponse(w, updateResp)
 }
+
+func (s *Server) handleAddMoodType(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Admin add mood type")
+
+	resp, err := s.MoodService.AddType(r)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to send request to mood service", err, http.StatusInternalServerError)
+		return
+	}
+
+	s.forwardResponse(w, resp)
+}
+
+func (s *Server) handleUpdateMoodType(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Admin update mood type")
+
+	resp, err := s.MoodService.UpdateType(r)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to send request to mood service", err, http.StatusInternalServerError)
+		return
+	}
+
+	s.forwardResponse(w, resp)
+}
+
+func (s *Server) handleDeleteMoodType(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Admin delete mood type")
+
+	resp, err := s.MoodService.DeleteType(r)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to send request to mood service", err, http.StatusInternalServerError)
+		return
+	}
+
+	s.forwardResponse(w, resp)
+}
+
+func (s *Server) handleGetTags(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Getting tags")
+
+	userID, ok := getUserIDFromContext(r.Context())
+	if !ok {
+		httputil.HandleError(*s.Logger, w, "Failed to get user ID from context", nil, http.StatusUnauthorized)
+		return
+	}
+
+	resp, err := s.MoodService.GetTags(userID)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to send request to mood service", err, http.StatusInternalServerError)
+		return
+	}
+
+	s.forwardResponse(w, resp)
+}
+
+type tagInput struct {
+	Name string `json:"name" validate:"required,max=30"`
+}
+
+func (s *Server) handleAddTag(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Adding tag")
+	var input tagInput
+
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Invalid request payload", err, http.StatusBadRequest)
+		return
+	}
+
+	err = s.Validator.Struct(input)
+	if err != nil {
+		httputil.HandleValidationError(*s.Logger, w, err)
+		return
+	}
+
+	userID, ok := getUserIDFromContext(r.Context())
+	if !ok {
+		httputil.HandleError(*s.Logger, w, "Failed to get user ID from context", nil, http.StatusUnauthorized)
+		return
+	}
+
+	bodyBytes, err := json.Marshal(map[string]interface{}{
+		"userId": userID,
+		"name":   input.Name,
+	})
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to marshal request body", err, http.StatusInternalServerError)
+		return
+	}
+
+	resp, err := s.MoodService.AddTag(bodyBytes)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to send request to mood service", err, http.StatusInternalServerError)
+		return
+	}
+
+	s.forwardResponse(w, resp)
+}
+
+func (s *Server) handleRenameTag(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Renaming tag")
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Invalid id parameter", err, http.StatusBadRequest)
+		return
+	}
+
+	var input tagInput
+	err = json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Invalid request payload", err, http.StatusBadRequest)
+		return
+	}
+
+	err = s.Validator.Struct(input)
+	if err != nil {
+		httputil.HandleValidationError(*s.Logger, w, err)
+		return
+	}
+
+	userID, ok := getUserIDFromContext(r.Context())
+	if !ok {
+		httputil.HandleError(*s.Logger, w, "Failed to get user ID from context", nil, http.StatusUnauthorized)
+		return
+	}
+
+	// The mood service only renames tags of the given user, so no ownership lookup is needed
+	bodyBytes, err := json.Marshal(map[string]interface{}{
+		"userId": userID,
+		"name":   input.Name,
+	})
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to marshal request body", err, http.StatusInternalServerError)
+		return
+	}
+
+	resp, err := s.MoodService.RenameTag(id, bodyBytes)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to send request to mood service", err, http.StatusInternalServerError)
+		return
+	}
+
+	s.forwardResponse(w, resp)
+}
+
+func (s *Server) handleDeleteTag(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Deleting tag")
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Invalid id parameter", err, http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := getUserIDFromContext(r.Context())
+	if !ok {
+		httputil.HandleError(*s.Logger, w, "Failed to get user ID from context", nil, http.StatusUnauthorized)
+		return
+	}
+
+	resp, err := s.MoodService.DeleteTag(id, userID)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to send request to mood service", err, http.StatusInternalServerError)
+		return
+	}
+
+	s.forwardResponse(w, resp)
+}
@@ -3,14 +3,35 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"slices"
+	"strings"
 
+	"github.com/ciameksw/mood-api/gateway/internal/gateway/tokenverifier"
 	"github.com/ciameksw/mood-api/pkg/httputil"
+	"github.com/ciameksw/mood-api/pkg/localtime"
 )
 
 type contextKey string
 
-const userIDContextKey contextKey = "userID"
+const (
+	userIDContextKey contextKey = "userID"
+	scopesContextKey contextKey = "scopes"
+	rolesContextKey  contextKey = "roles"
+)
+
+// personalAccessTokenPrefix marks tokens that only the auth service can check
+const personalAccessTokenPrefix = "mpat_"
+
+// principal is the authenticated caller, Scopes is nil for logged users with full access
+type principal struct {
+	UserID    int
+	Scopes    []string
+	Roles     []string
+	Timezone  string
+	WeekStart string
+}
 
 // authMiddleware checks for valid authorization token
 func (s *Server) authMiddleware(next http.HandlerFunc) http.HandlerFunc {
@@ -22,35 +43,128 @@ func (s *Server) authMiddleware(next http.HandlerFunc) http.HandlerFunc {
 			return
 		}
 
-		resp, err := s.AuthService.Authorize(authHeader)
+		p, err := s.authorize(authHeader)
 		if err != nil {
-			httputil.HandleError(*s.Logger, w, "Unauthorized", nil, http.StatusUnauthorized)
+			httputil.HandleError(*s.Logger, w, "Unauthorized", err, http.StatusUnauthorized)
 			return
 		}
-		defer resp.Body.Close()
 
-		if resp.StatusCode != http.StatusOK {
-			httputil.HandleError(*s.Logger, w, "Unauthorized", nil, http.StatusUnauthorized)
-			return
+		// Attach user id, scopes and roles to context and proceed
+		ctx := context.WithValue(r.Context(), userIDContextKey, p.UserID)
+		if p.Scopes != nil {
+			ctx = context.WithValue(ctx, scopesContextKey, p.Scopes)
 		}
+		ctx = context.WithValue(ctx, rolesContextKey, p.Roles)
 
-		// Parse userId from auth service response
-		var body struct {
-			UserID int `json:"userId"`
+		// The user's preferences replace whatever the client sent, services read them from these headers
+		setPreferenceHeader(r, localtime.TimezoneHeader, p.Timezone)
+		setPreferenceHeader(r, localtime.WeekStartHeader, p.WeekStart)
+		next(w, r.WithContext(ctx))
+	}
+}
+
+// requireScope rejects personal access tokens that were not granted scope, must be wrapped by authMiddleware
+func (s *Server) requireScope(scope string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		scopes, restricted := getScopesFromContext(r.Context())
+		if restricted && !slices.Contains(scopes, scope) {
+			httputil.HandleError(*s.Logger, w, "Forbidden: token is missing scope "+scope, nil, http.StatusForbidden)
+			return
 		}
-		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
-			httputil.HandleError(*s.Logger, w, "Unauthorized", nil, http.StatusUnauthorized)
+
+		next(w, r)
+	}
+}
+
+// requireFullAccess rejects personal access tokens limited to scopes, must be wrapped by authMiddleware
+func (s *Server) requireFullAccess(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, restricted := getScopesFromContext(r.Context()); restricted {
+			httputil.HandleError(*s.Logger, w, "Forbidden: token is limited to scopes", nil, http.StatusForbidden)
 			return
 		}
-		if body.UserID == 0 {
-			httputil.HandleError(*s.Logger, w, "Unauthorized", nil, http.StatusUnauthorized)
+
+		next(w, r)
+	}
+}
+
+// requireRole authenticates the request and rejects callers without role. Personal access tokens never carry roles.
+func (s *Server) requireRole(role string, next http.HandlerFunc) http.HandlerFunc {
+	return s.authMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if !slices.Contains(getRolesFromContext(r.Context()), role) {
+			httputil.HandleError(*s.Logger, w, "Forbidden: "+role+" role required", nil, http.StatusForbidden)
 			return
 		}
 
-		// Attach user id to context and proceed
-		ctx := context.WithValue(r.Context(), userIDContextKey, body.UserID)
-		next(w, r.WithContext(ctx))
+		next(w, r)
+	})
+}
+
+// authorize verifies the token locally when possible and falls back to the auth service otherwise
+func (s *Server) authorize(authHeader string) (*principal, error) {
+	tokenString := strings.TrimPrefix(authHeader, "Bearer ")
+	if s.TokenVerifier != nil && !strings.HasPrefix(tokenString, personalAccessTokenPrefix) {
+		claims, err := s.TokenVerifier.Verify(tokenString)
+		if err == nil {
+			return &principal{UserID: claims.UserID, Roles: claims.Roles, Timezone: claims.Timezone, WeekStart: claims.WeekStart}, nil
+		}
+
+		// Only situations where the auth service may know better are retried remotely
+		if !errors.Is(err, tokenverifier.ErrUnknownKey) && !errors.Is(err, tokenverifier.ErrStaleRevocations) {
+			return nil, err
+		}
+		s.Logger.Info.Printf("Falling back to remote authorization: %v", err)
 	}
+
+	return s.authorizeRemote(authHeader)
+}
+
+// authorizeRemote asks the auth service to validate the token
+func (s *Server) authorizeRemote(authHeader string) (*principal, error) {
+	resp, err := s.AuthService.Authorize(authHeader)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New("auth service rejected the token")
+	}
+
+	// Parse userId, roles and, for personal access tokens, scopes from auth service response
+	var body struct {
+		UserID    int      `json:"userId"`
+		TokenType string   `json:"tokenType"`
+		Scopes    []string `json:"scopes"`
+		Roles     []string `json:"roles"`
+		Timezone  string   `json:"timezone"`
+		WeekStart string   `json:"weekStart"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+	if body.UserID == 0 {
+		return nil, errors.New("auth service returned no user id")
+	}
+
+	p := &principal{UserID: body.UserID, Roles: body.Roles, Timezone: body.Timezone, WeekStart: body.WeekStart}
+	if body.TokenType == "personal" {
+		p.Scopes = body.Scopes
+		if p.Scopes == nil {
+			p.Scopes = []string{}
+		}
+	}
+
+	return p, nil
+}
+
+// Helper function to set a preference header, tokens issued before preferences existed carry none
+func setPreferenceHeader(r *http.Request, key, value string) {
+	if value == "" {
+		r.Header.Del(key)
+		return
+	}
+	r.Header.Set(key, value)
 }
 
 // getUserIDFromContext retrieves the authenticated user id set by authMiddleware
@@ -62,3 +176,15 @@ func getUserIDFromContext(ctx context.Context) (int, bool) {
 	id, ok := v.(int)
 	return id, ok
 }
+
+// getScopesFromContext retrieves the scopes of a personal access token, restricted is false for full-access tokens
+func getScopesFromContext(ctx context.Context) (scopes []string, restricted bool) {
+	scopes, restricted = ctx.Value(scopesContextKey).([]string)
+	return scopes, restricted
+}
+
+// getRolesFromContext retrieves the roles of the authenticated user set by authMiddleware
+func getRolesFromContext(ctx context.Context) []string {
+	roles, _ := ctx.Value(rolesContextKey).([]string)
+	return roles
+}
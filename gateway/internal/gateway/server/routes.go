@@ -3,27 +3,77 @@ package server
 import "net/http"
 
 func (s *Server) setupAuthRouter(r *http.ServeMux) {
-	r.HandleFunc("POST /auth/register", s.handleRegister)                   // Register to the system
-	r.HandleFunc("POST /auth/login", s.handleLogin)                         // Login to get auth token
-	r.HandleFunc("GET /auth/user", s.authMiddleware(s.handleGetUser))       // Get logged user info
-	r.HandleFunc("PUT /auth/user", s.authMiddleware(s.handleUpdateUser))    // Update logged user info
-	r.HandleFunc("DELETE /auth/user", s.authMiddleware(s.handleDeleteUser)) // Delete logged user account
+	r.HandleFunc("POST /auth/register", s.handleRegister)                                                 // Register to the system
+	r.HandleFunc("POST /auth/login", s.handleLogin)                                                       // Login to get auth token
+	r.HandleFunc("POST /auth/refresh", s.handleRefresh)                                                   // Exchange refresh token for a new token pair
+	r.HandleFunc("POST /auth/password/forgot", s.handleForgotPassword)                                    // Request a password reset email
+	r.HandleFunc("POST /auth/password/reset", s.handleResetPassword)                                      // Set a new password with a reset token
+	r.HandleFunc("POST /auth/password/change", s.authMiddleware(s.handleChangePassword))                  // Change the password of the logged user
+	r.HandleFunc("POST /auth/magic-link", s.handleRequestMagicLink)                                       // Request a login link by email
+	r.HandleFunc("POST /auth/magic-link/consume", s.handleConsumeMagicLink)                               // Exchange a login link for tokens
+	r.HandleFunc("GET /auth/verify", s.handleVerifyEmail)                                                 // Confirm an email address with a verification token
+	r.HandleFunc("POST /auth/verify/resend", s.handleResendVerification)                                  // Request a new verification email
+	r.HandleFunc("POST /auth/2fa/verify", s.handleTwoFactorVerify)                                        // Exchange a login challenge and a code for tokens
+	r.HandleFunc("POST /auth/2fa/setup", s.authMiddleware(s.handleTwoFactorSetup))                        // Start two-factor enrollment
+	r.HandleFunc("POST /auth/2fa/confirm", s.authMiddleware(s.handleTwoFactorConfirm))                    // Enable two-factor authentication with a first code
+	r.HandleFunc("POST /auth/2fa/recovery-codes", s.authMiddleware(s.handleRegenerateRecoveryCodes))      // Replace the recovery codes
+	r.HandleFunc("POST /auth/2fa/disable", s.authMiddleware(s.handleTwoFactorDisable))                    // Disable two-factor authentication
+	r.HandleFunc("GET /auth/oidc/providers", s.handleGetOIDCProviders)                                    // List identity providers available for login
+	r.HandleFunc("GET /auth/oidc/{provider}/login", s.handleOIDCLogin)                                    // Redirect to an identity provider to sign in
+	r.HandleFunc("GET /auth/oidc/{provider}/callback", s.handleOIDCCallback)                              // Finish an identity provider sign-in and get tokens
+	r.HandleFunc("GET /auth/.well-known/jwks.json", s.handleJWKS)                                         // Public keys for verifying tokens
+	r.HandleFunc("POST /auth/logout", s.authMiddleware(s.handleLogout))                                   // Revoke the current token
+	r.HandleFunc("POST /auth/logout-all", s.authMiddleware(s.handleLogoutAll))                            // Revoke every token of the logged user
+	r.HandleFunc("POST /auth/tokens", s.authMiddleware(s.handleCreatePersonalToken))                      // Create a personal access token
+	r.HandleFunc("GET /auth/tokens", s.authMiddleware(s.handleGetPersonalTokens))                         // List personal access tokens of the logged user
+	r.HandleFunc("DELETE /auth/tokens/{id}", s.authMiddleware(s.handleDeletePersonalToken))               // Revoke a personal access token
+	r.HandleFunc("GET /auth/sessions", s.authMiddleware(s.handleGetSessions))                             // List active sessions of the logged user
+	r.HandleFunc("DELETE /auth/sessions/{id}", s.authMiddleware(s.handleDeleteSession))                   // Revoke a session of the logged user
+	r.HandleFunc("GET /auth/admin/users", s.requireRole("admin", s.handleAdminGetUsers))                  // List users with their roles (admin)
+	r.HandleFunc("PUT /auth/admin/users/{id}/roles", s.requireRole("admin", s.handleAdminSetUserRoles))   // Replace the roles of a user (admin)
+	r.HandleFunc("POST /auth/admin/users/{id}/restore", s.requireRole("admin", s.handleAdminRestoreUser)) // Cancel the scheduled deletion of a user (admin)
+	r.HandleFunc("GET /auth/admin/events", s.requireRole("admin", s.handleAdminGetEvents))                // Query authentication events of all users (admin)
+	r.HandleFunc("GET /auth/events", s.authMiddleware(s.handleGetEvents))                                 // List recent security events of the logged user
+	r.HandleFunc("GET /auth/user", s.authMiddleware(s.handleGetUser))                                     // Get logged user info
+	r.HandleFunc("PUT /auth/user", s.authMiddleware(s.handleUpdateUser))                                  // Update logged user info
+	r.HandleFunc("GET /auth/user/preferences", s.authMiddleware(s.handleGetPreferences))                  // Get preferences of the logged user
+	r.HandleFunc("PUT /auth/user/preferences", s.authMiddleware(s.handleUpdatePreferences))               // Update preferences of the logged user
+	r.HandleFunc("DELETE /auth/user", s.authMiddleware(s.handleDeleteUser))                               // Schedule the logged user account for deletion
+	r.HandleFunc("POST /auth/user/restore", s.handleRestoreUser)                                          // Cancel an account deletion with a restore token
 }
 
 func (s *Server) setupMoodRouter(r *http.ServeMux) {
-	r.HandleFunc("POST /mood", s.authMiddleware(s.handleAddMood))               // Add new mood entry to the logged user
-	r.HandleFunc("GET /mood", s.authMiddleware(s.handleGetMoods))               // Get mood entries of the logged user in time range
-	r.HandleFunc("GET /mood/types", s.authMiddleware(s.handleGetMoodTypes))     // Get all available mood types
-	r.HandleFunc("GET /mood/summary", s.authMiddleware(s.handleGetMoodSummary)) // Get mood summary for the logged user in time range
-	r.HandleFunc("GET /mood/{id}", s.authMiddleware(s.handleGetMood))           // Get single mood entry by id
-	r.HandleFunc("PUT /mood", s.authMiddleware(s.handleUpdateMood))             // Update a mood entry of the logged user
-	r.HandleFunc("DELETE /mood/{id}", s.authMiddleware(s.handleDeleteMood))     // Delete a mood entry of the logged user
+	r.HandleFunc("POST /mood", s.authMiddleware(s.requireScope("mood:write", s.handleAddMood)))               // Add new mood entry to the logged user
+	r.HandleFunc("GET /mood", s.authMiddleware(s.requireScope("mood:read", s.handleGetMoods)))                // Get a page of mood entries of the logged user
+	r.HandleFunc("GET /mood/types", s.authMiddleware(s.requireScope("mood:read", s.handleGetMoodTypes)))      // Get all available mood types
+	r.HandleFunc("POST /mood/types", s.requireRole("admin", s.handleAddMoodType))                             // Add a mood type (admin)
+	r.HandleFunc("PUT /mood/types/{id}", s.requireRole("admin", s.handleUpdateMoodType))                      // Update a mood type (admin)
+	r.HandleFunc("DELETE /mood/types/{id}", s.requireRole("admin", s.handleDeleteMoodType))                   // Delete an unused mood type (admin)
+	r.HandleFunc("GET /mood/summary", s.authMiddleware(s.requireScope("mood:read", s.handleGetMoodSummary)))  // Get mood summary for the logged user in time range
+	r.HandleFunc("GET /mood/search", s.authMiddleware(s.requireScope("mood:read", s.handleSearchMoods)))      // Search the notes of the logged user's mood entries
+	r.HandleFunc("GET /mood/tags", s.authMiddleware(s.requireScope("mood:read", s.handleGetTags)))            // List tags of the logged user
+	r.HandleFunc("POST /mood/tags", s.authMiddleware(s.requireScope("mood:write", s.handleAddTag)))           // Add a tag for the logged user
+	r.HandleFunc("PUT /mood/tags/{id}", s.authMiddleware(s.requireScope("mood:write", s.handleRenameTag)))    // Rename a tag of the logged user
+	r.HandleFunc("DELETE /mood/tags/{id}", s.authMiddleware(s.requireScope("mood:write", s.handleDeleteTag))) // Delete a tag of the logged user
+	r.HandleFunc("GET /mood/{id}", s.authMiddleware(s.requireScope("mood:read", s.handleGetMood)))            // Get single mood entry by id
+	r.HandleFunc("PUT /mood", s.authMiddleware(s.requireScope("mood:write", s.handleUpdateMood)))             // Update a mood entry of the logged user
+	r.HandleFunc("DELETE /mood/{id}", s.authMiddleware(s.requireScope("mood:write", s.handleDeleteMood)))     // Delete a mood entry of the logged user
 }
 
 func (s *Server) setupAdviceRouter(r *http.ServeMux) {
-	r.HandleFunc("GET /advice", s.authMiddleware(s.handleGetAdvice)) // Get advice for the logged user
+	r.HandleFunc("GET /advice", s.authMiddleware(s.requireScope("advice:read", s.handleGetAdvice)))  // Get advice for the logged user
+	r.HandleFunc("GET /advice/types", s.requireRole("admin", s.handleGetAdviceTypes))                // List advice types (admin)
+	r.HandleFunc("GET /advice/catalog", s.requireRole("admin", s.handleGetAdviceCatalog))            // List every advice in the catalog (admin)
+	r.HandleFunc("POST /advice/catalog", s.requireRole("admin", s.handleAddCatalogAdvice))           // Add advice to the catalog (admin)
+	r.HandleFunc("PUT /advice/catalog/{id}", s.requireRole("admin", s.handleUpdateCatalogAdvice))    // Update advice in the catalog (admin)
+	r.HandleFunc("DELETE /advice/catalog/{id}", s.requireRole("admin", s.handleDeleteCatalogAdvice)) // Delete advice not given to anyone yet (admin)
 }
 
 func (s *Server) setupQuoteRouter(r *http.ServeMux) {
-	r.HandleFunc("GET /quote/today", s.authMiddleware(s.handleGetTodayQuote)) // Get todays quote for the logged user
+	r.HandleFunc("GET /quote/today", s.authMiddleware(s.requireScope("quote:read", s.handleGetTodayQuote))) // Get todays quote for the logged user
+}
+
+func (s *Server) setupExportRouter(r *http.ServeMux) {
+	r.HandleFunc("POST /export", s.authMiddleware(s.requireFullAccess(s.handleCreateExport)))  // Start an export of all data of the logged user
+	r.HandleFunc("GET /export/{id}", s.authMiddleware(s.requireFullAccess(s.handleGetExport))) // Get the status of an export or download it once completed
 }
@@ -5,10 +5,13 @@ import (
 	"net/http"
 
 	"github.com/ciameksw/mood-api/gateway/internal/gateway/config"
+	"github.com/ciameksw/mood-api/gateway/internal/gateway/export"
 	"github.com/ciameksw/mood-api/gateway/internal/gateway/services/advice"
 	"github.com/ciameksw/mood-api/gateway/internal/gateway/services/auth"
 	"github.com/ciameksw/mood-api/gateway/internal/gateway/services/mood"
 	"github.com/ciameksw/mood-api/gateway/internal/gateway/services/quote"
+	"github.com/ciameksw/mood-api/gateway/internal/gateway/tokenverifier"
+	"github.com/ciameksw/mood-api/pkg/httputil"
 	"github.com/ciameksw/mood-api/pkg/logger"
 	"github.com/go-playground/validator/v10"
 )
@@ -20,39 +23,62 @@ type Server struct {
 	MoodService   *mood.MoodService
 	AdviceService *advice.AdviceService
 	QuoteService  *quote.QuoteService
+	TokenVerifier *tokenverifier.TokenVerifier // nil when every token is checked by the auth service
+	Exports       *export.Store
 	Validator     *validator.Validate
 	httpServer    *http.Server
 }
 
 func NewServer(log *logger.Logger, cfg *config.Config) *Server {
-	return &Server{
+	s := &Server{
 		Logger:        log,
 		Config:        cfg,
 		AuthService:   auth.NewAuthService(cfg),
 		MoodService:   mood.NewMoodService(cfg),
 		AdviceService: advice.NewAdviceService(cfg),
 		QuoteService:  quote.NewQuoteService(cfg),
-		Validator:     validator.New(),
+		Exports:       export.NewStore(cfg, log),
+		Validator:     httputil.NewValidator(),
 	}
+
+	if cfg.AuthLocalVerification {
+		s.TokenVerifier = tokenverifier.NewTokenVerifier(cfg, log)
+	}
+
+	return s
 }
 
-func (s *Server) Start() error {
+// StartBackground starts the background workers of the gateway until ctx is cancelled
+func (s *Server) StartBackground(ctx context.Context) {
+	if s.TokenVerifier != nil {
+		s.TokenVerifier.Start(ctx)
+	}
+	s.Exports.Start(ctx)
+}
+
+// Handler builds the router with every gateway route
+func (s *Server) Handler() http.Handler {
 	r := http.NewServeMux()
 
 	s.setupAuthRouter(r)
 	s.setupMoodRouter(r)
 	s.setupAdviceRouter(r)
 	s.setupQuoteRouter(r)
+	s.setupExportRouter(r)
 
 	r.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("OK"))
 	})
 
+	return r
+}
+
+func (s *Server) Start() error {
 	addr := s.Config.ServerHost + ":" + s.Config.ServerPort
 	s.httpServer = &http.Server{
 		Addr:    addr,
-		Handler: r,
+		Handler: s.Handler(),
 	}
 
 	s.Logger.Info.Printf("Starting server on %s", addr)
@@ -69,3 +69,54 @@ func (as *AdviceService) GetByPeriod(from, to string, userID int) (*http.Respons
 
 	return resp, nil
 }
+
+// ExportUserAdvice streams the advice history of the user as NDJSON
+func (as *AdviceService) ExportUserAdvice(userID int) (*http.Response, error) {
+	params := httpclient.RequestParams{
+		URL:    as.AdviceURL + "/advice/user/" + strconv.Itoa(userID) + "/export",
+		Method: http.MethodGet,
+	}
+	resp, err := httpclient.SendRequest(params)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func (as *AdviceService) GetTypes(r *http.Request) (*http.Response, error) {
+	return as.commonServiceFunc("/advice/types", r)
+}
+
+func (as *AdviceService) GetCatalog(r *http.Request) (*http.Response, error) {
+	return as.commonServiceFunc("/advice/catalog", r)
+}
+
+func (as *AdviceService) AddCatalogAdvice(r *http.Request) (*http.Response, error) {
+	return as.commonServiceFunc("/advice/catalog", r)
+}
+
+func (as *AdviceService) UpdateCatalogAdvice(r *http.Request) (*http.Response, error) {
+	return as.commonServiceFunc("/advice/catalog/"+url.PathEscape(r.PathValue("id")), r)
+}
+
+func (as *AdviceService) DeleteCatalogAdvice(r *http.Request) (*http.Response, error) {
+	return as.commonServiceFunc("/advice/catalog/"+url.PathEscape(r.PathValue("id")), r)
+}
+
+// Helper function to pass the request through to the advice service unchanged
+func (as *AdviceService) commonServiceFunc(path string, r *http.Request) (*http.Response, error) {
+	ct := r.Header.Get("Content-Type")
+	params := httpclient.RequestParams{
+		URL:         as.AdviceURL + path,
+		Method:      r.Method,
+		Body:        r.Body,
+		ContentType: &ct,
+	}
+	resp, err := httpclient.SendRequest(params)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
@@ -1,19 +1,24 @@
 package auth
 
 import (
+	"net"
 	"net/http"
+	"net/url"
+	"strings"
 
 	"github.com/ciameksw/mood-api/gateway/internal/gateway/config"
 	"github.com/ciameksw/mood-api/gateway/internal/gateway/httpclient"
 )
 
 type AuthService struct {
-	AuthURL string
+	AuthURL           string
+	TrustProxyHeaders bool
 }
 
 func NewAuthService(cfg *config.Config) *AuthService {
 	return &AuthService{
-		AuthURL: cfg.AuthURL,
+		AuthURL:           cfg.AuthURL,
+		TrustProxyHeaders: cfg.TrustProxyHeaders,
 	}
 }
 
@@ -25,6 +30,10 @@ func (as *AuthService) Login(r *http.Request) (*http.Response, error) {
 	return as.commonServiceFunc("/auth/login", r)
 }
 
+func (as *AuthService) Refresh(r *http.Request) (*http.Response, error) {
+	return as.commonServiceFunc("/auth/refresh", r)
+}
+
 func (as *AuthService) Authorize(authHeader string) (*http.Response, error) {
 	params := httpclient.RequestParams{
 		URL:           as.AuthURL + "/auth/authorize",
@@ -39,6 +48,118 @@ func (as *AuthService) Authorize(authHeader string) (*http.Response, error) {
 	return resp, nil
 }
 
+func (as *AuthService) ForgotPassword(r *http.Request) (*http.Response, error) {
+	return as.commonServiceFunc("/auth/password/forgot", r)
+}
+
+func (as *AuthService) ResetPassword(r *http.Request) (*http.Response, error) {
+	return as.commonServiceFunc("/auth/password/reset", r)
+}
+
+func (as *AuthService) ChangePassword(r *http.Request) (*http.Response, error) {
+	return as.commonServiceFunc("/auth/password/change", r)
+}
+
+func (as *AuthService) RequestMagicLink(r *http.Request) (*http.Response, error) {
+	return as.commonServiceFunc("/auth/magic-link", r)
+}
+
+func (as *AuthService) ConsumeMagicLink(r *http.Request) (*http.Response, error) {
+	return as.commonServiceFunc("/auth/magic-link/consume", r)
+}
+
+func (as *AuthService) VerifyEmail(r *http.Request) (*http.Response, error) {
+	return as.commonServiceFunc("/auth/verify?"+r.URL.RawQuery, r)
+}
+
+func (as *AuthService) ResendVerification(r *http.Request) (*http.Response, error) {
+	return as.commonServiceFunc("/auth/verify/resend", r)
+}
+
+func (as *AuthService) TwoFactorVerify(r *http.Request) (*http.Response, error) {
+	return as.commonServiceFunc("/auth/2fa/verify", r)
+}
+
+func (as *AuthService) TwoFactorSetup(r *http.Request) (*http.Response, error) {
+	return as.commonServiceFunc("/auth/2fa/setup", r)
+}
+
+func (as *AuthService) TwoFactorConfirm(r *http.Request) (*http.Response, error) {
+	return as.commonServiceFunc("/auth/2fa/confirm", r)
+}
+
+func (as *AuthService) RegenerateRecoveryCodes(r *http.Request) (*http.Response, error) {
+	return as.commonServiceFunc("/auth/2fa/recovery-codes", r)
+}
+
+func (as *AuthService) TwoFactorDisable(r *http.Request) (*http.Response, error) {
+	return as.commonServiceFunc("/auth/2fa/disable", r)
+}
+
+func (as *AuthService) GetJWKS(r *http.Request) (*http.Response, error) {
+	return as.commonServiceFunc("/auth/.well-known/jwks.json", r)
+}
+
+func (as *AuthService) Logout(r *http.Request) (*http.Response, error) {
+	return as.commonServiceFunc("/auth/logout", r)
+}
+
+func (as *AuthService) LogoutAll(r *http.Request) (*http.Response, error) {
+	return as.commonServiceFunc("/auth/logout-all", r)
+}
+
+func (as *AuthService) CreatePersonalToken(r *http.Request) (*http.Response, error) {
+	return as.commonServiceFunc("/auth/tokens", r)
+}
+
+func (as *AuthService) GetPersonalTokens(r *http.Request) (*http.Response, error) {
+	return as.commonServiceFunc("/auth/tokens", r)
+}
+
+func (as *AuthService) DeletePersonalToken(r *http.Request) (*http.Response, error) {
+	return as.commonServiceFunc("/auth/tokens/"+url.PathEscape(r.PathValue("id")), r)
+}
+
+func (as *AuthService) GetSessions(r *http.Request) (*http.Response, error) {
+	return as.commonServiceFunc("/auth/sessions", r)
+}
+
+func (as *AuthService) DeleteSession(r *http.Request) (*http.Response, error) {
+	return as.commonServiceFunc("/auth/sessions/"+url.PathEscape(r.PathValue("id")), r)
+}
+
+func (as *AuthService) GetOIDCProviders(r *http.Request) (*http.Response, error) {
+	return as.commonServiceFunc("/auth/oidc/providers", r)
+}
+
+func (as *AuthService) OIDCLogin(r *http.Request) (*http.Response, error) {
+	return as.commonServiceFunc("/auth/oidc/"+url.PathEscape(r.PathValue("provider"))+"/login", r)
+}
+
+func (as *AuthService) OIDCCallback(r *http.Request) (*http.Response, error) {
+	return as.commonServiceFunc("/auth/oidc/"+url.PathEscape(r.PathValue("provider"))+"/callback?"+r.URL.RawQuery, r)
+}
+
+func (as *AuthService) GetUsers(r *http.Request) (*http.Response, error) {
+	return as.commonServiceFunc("/auth/admin/users", r)
+}
+
+func (as *AuthService) SetUserRoles(r *http.Request) (*http.Response, error) {
+	return as.commonServiceFunc("/auth/admin/users/"+url.PathEscape(r.PathValue("id"))+"/roles", r)
+}
+
+func (as *AuthService) AdminRestoreUser(r *http.Request) (*http.Response, error) {
+	return as.commonServiceFunc("/auth/admin/users/"+url.PathEscape(r.PathValue("id"))+"/restore", r)
+}
+
+func (as *AuthService) AdminGetEvents(r *http.Request) (*http.Response, error) {
+	return as.commonServiceFunc("/auth/admin/events?"+r.URL.RawQuery, r)
+}
+
+func (as *AuthService) GetEvents(r *http.Request) (*http.Response, error) {
+	return as.commonServiceFunc("/auth/events?"+r.URL.RawQuery, r)
+}
+
 func (as *AuthService) GetLoggedUser(r *http.Request) (*http.Response, error) {
 	return as.commonServiceFunc("/auth/user", r)
 }
@@ -47,10 +168,46 @@ func (as *AuthService) UpdateLoggedUser(r *http.Request) (*http.Response, error)
 	return as.commonServiceFunc("/auth/user", r)
 }
 
+func (as *AuthService) GetPreferences(r *http.Request) (*http.Response, error) {
+	return as.commonServiceFunc("/auth/user/preferences", r)
+}
+
+func (as *AuthService) UpdatePreferences(r *http.Request) (*http.Response, error) {
+	return as.commonServiceFunc("/auth/user/preferences", r)
+}
+
 func (as *AuthService) DeleteLoggedUser(r *http.Request) (*http.Response, error) {
 	return as.commonServiceFunc("/auth/user", r)
 }
 
+func (as *AuthService) RestoreUser(r *http.Request) (*http.Response, error) {
+	return as.commonServiceFunc("/auth/user/restore", r)
+}
+
+// GetUserForExport fetches the profile of the token's user outside of a client request, for the data export
+func (as *AuthService) GetUserForExport(authHeader string) (*http.Response, error) {
+	return as.getWithToken("/auth/user", authHeader)
+}
+
+// GetPreferencesForExport fetches the preferences of the token's user outside of a client request, for the data export
+func (as *AuthService) GetPreferencesForExport(authHeader string) (*http.Response, error) {
+	return as.getWithToken("/auth/user/preferences", authHeader)
+}
+
+func (as *AuthService) getWithToken(path, authHeader string) (*http.Response, error) {
+	params := httpclient.RequestParams{
+		URL:           as.AuthURL + path,
+		Method:        http.MethodGet,
+		Authorization: &authHeader,
+	}
+	resp, err := httpclient.SendRequest(params)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
 func (as *AuthService) commonServiceFunc(url string, r *http.Request) (*http.Response, error) {
 	ct := r.Header.Get("Content-Type")
 	authHeader := r.Header.Get("Authorization")
@@ -60,6 +217,15 @@ func (as *AuthService) commonServiceFunc(url string, r *http.Request) (*http.Res
 		Body:          r.Body,
 		ContentType:   &ct,
 		Authorization: &authHeader,
+		// Client details are used for brute-force protection and the session list
+		Headers: map[string]string{
+			"X-Real-IP":  as.clientIP(r),
+			"User-Agent": r.UserAgent(),
+		},
+	}
+	// The identity provider login keeps its state in a cookie
+	if cookie := r.Header.Get("Cookie"); cookie != "" {
+		params.Headers["Cookie"] = cookie
 	}
 	resp, err := httpclient.SendRequest(params)
 	if err != nil {
@@ -68,3 +234,22 @@ func (as *AuthService) commonServiceFunc(url string, r *http.Request) (*http.Res
 
 	return resp, nil
 }
+
+// Helper function to get the client IP of the request
+func (as *AuthService) clientIP(r *http.Request) string {
+	// Proxy headers can be forged by clients, so they are only used behind a trusted proxy
+	if as.TrustProxyHeaders {
+		if ip := strings.TrimSpace(r.Header.Get("X-Real-IP")); ip != "" {
+			return ip
+		}
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			return strings.TrimSpace(strings.Split(forwarded, ",")[0])
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -20,13 +20,15 @@ func NewMoodService(cfg *config.Config) *MoodService {
 	}
 }
 
-func (ms *MoodService) Add(body []byte) (*http.Response, error) {
+// Add creates a mood entry, headers carry the user's time zone for entries without a date
+func (ms *MoodService) Add(body []byte, headers map[string]string) (*http.Response, error) {
 	ct := "application/json"
 	params := httpclient.RequestParams{
 		URL:         ms.MoodURL + "/mood",
 		Method:      http.MethodPost,
 		Body:        bytes.NewBuffer(body),
 		ContentType: &ct,
+		Headers:     headers,
 	}
 	resp, err := httpclient.SendRequest(params)
 	if err != nil {
@@ -52,11 +54,19 @@ func (ms *MoodService) GetTypes(r *http.Request) (*http.Response, error) {
 	return resp, nil
 }
 
-func (ms *MoodService) GetSummary(from, to string, userID int) (*http.Response, error) {
+// GetSummary counts the moods of the user in the range, rollup picks the check-ins of each day and is left to the service when empty,
+// byTag adds the distribution of each tag
+func (ms *MoodService) GetSummary(from, to string, userID int, rollup string, byTag bool) (*http.Response, error) {
 	q := url.Values{}
 	q.Set("from", from)
 	q.Set("to", to)
 	q.Set("userId", strconv.Itoa(userID))
+	if rollup != "" {
+		q.Set("rollup", rollup)
+	}
+	if byTag {
+		q.Set("byTag", "true")
+	}
 
 	params := httpclient.RequestParams{
 		URL:    ms.MoodURL + "/mood/summary?" + q.Encode(),
@@ -70,10 +80,17 @@ func (ms *MoodService) GetSummary(from, to string, userID int) (*http.Response,
 	return resp, nil
 }
 
-func (ms *MoodService) GetMoods(from, to string, userID int) (*http.Response, error) {
+// GetMoods lists a page of the user's mood entries, query holds the range, page and tag parameters passed through
+func (ms *MoodService) GetMoods(userID int, query url.Values) (*http.Response, error) {
 	q := url.Values{}
-	q.Set("from", from)
-	q.Set("to", to)
+	for _, key := range []string{"from", "to", "limit", "cursor", "sort"} {
+		if v := query.Get(key); v != "" {
+			q.Set(key, v)
+		}
+	}
+	for _, tag := range query["tag"] {
+		q.Add("tag", tag)
+	}
 	q.Set("userId", strconv.Itoa(userID))
 
 	params := httpclient.RequestParams{
@@ -88,6 +105,28 @@ func (ms *MoodService) GetMoods(from, to string, userID int) (*http.Response, er
 	return resp, nil
 }
 
+// Search finds mood entries of the user by their notes, query holds the search parameters passed through
+func (ms *MoodService) Search(userID int, query url.Values) (*http.Response, error) {
+	q := url.Values{}
+	for _, key := range []string{"q", "from", "to", "limit", "offset"} {
+		if v := query.Get(key); v != "" {
+			q.Set(key, v)
+		}
+	}
+	q.Set("userId", strconv.Itoa(userID))
+
+	params := httpclient.RequestParams{
+		URL:    ms.MoodURL + "/mood/search?" + q.Encode(),
+		Method: http.MethodGet,
+	}
+	resp, err := httpclient.SendRequest(params)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
 func (ms *MoodService) DeleteMood(moodID int) (*http.Response, error) {
 	params := httpclient.RequestParams{
 		URL:    ms.MoodURL + "/mood/" + strconv.Itoa(moodID),
@@ -129,3 +168,104 @@ func (ms *MoodService) Update(body []byte) (*http.Response, error) {
 
 	return resp, nil
 }
+
+// ExportUserMoods streams every mood entry of the user as NDJSON
+func (ms *MoodService) ExportUserMoods(userID int) (*http.Response, error) {
+	params := httpclient.RequestParams{
+		URL:    ms.MoodURL + "/mood/user/" + strconv.Itoa(userID) + "/export",
+		Method: http.MethodGet,
+	}
+	resp, err := httpclient.SendRequest(params)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func (ms *MoodService) GetTags(userID int) (*http.Response, error) {
+	params := httpclient.RequestParams{
+		URL:    ms.MoodURL + "/mood/tags?userId=" + strconv.Itoa(userID),
+		Method: http.MethodGet,
+	}
+	resp, err := httpclient.SendRequest(params)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func (ms *MoodService) AddTag(body []byte) (*http.Response, error) {
+	ct := "application/json"
+	params := httpclient.RequestParams{
+		URL:         ms.MoodURL + "/mood/tags",
+		Method:      http.MethodPost,
+		Body:        bytes.NewBuffer(body),
+		ContentType: &ct,
+	}
+	resp, err := httpclient.SendRequest(params)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func (ms *MoodService) RenameTag(tagID int, body []byte) (*http.Response, error) {
+	ct := "application/json"
+	params := httpclient.RequestParams{
+		URL:         ms.MoodURL + "/mood/tags/" + strconv.Itoa(tagID),
+		Method:      http.MethodPut,
+		Body:        bytes.NewBuffer(body),
+		ContentType: &ct,
+	}
+	resp, err := httpclient.SendRequest(params)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func (ms *MoodService) DeleteTag(tagID, userID int) (*http.Response, error) {
+	params := httpclient.RequestParams{
+		URL:    ms.MoodURL + "/mood/tags/" + strconv.Itoa(tagID) + "?userId=" + strconv.Itoa(userID),
+		Method: http.MethodDelete,
+	}
+	resp, err := httpclient.SendRequest(params)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func (ms *MoodService) AddType(r *http.Request) (*http.Response, error) {
+	return ms.commonServiceFunc("/mood/types", r)
+}
+
+func (ms *MoodService) UpdateType(r *http.Request) (*http.Response, error) {
+	return ms.commonServiceFunc("/mood/types/"+url.PathEscape(r.PathValue("id")), r)
+}
+
+func (ms *MoodService) DeleteType(r *http.Request) (*http.Response, error) {
+	return ms.commonServiceFunc("/mood/types/"+url.PathEscape(r.PathValue("id")), r)
+}
+
+// Helper function to pass the request through to the mood service unchanged
+func (ms *MoodService) commonServiceFunc(path string, r *http.Request) (*http.Response, error) {
+	ct := r.Header.Get("Content-Type")
+	params := httpclient.RequestParams{
+		URL:         ms.MoodURL + path,
+		Method:      r.Method,
+		Body:        r.Body,
+		ContentType: &ct,
+	}
+	resp, err := httpclient.SendRequest(params)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/ciameksw/mood-api/gateway/internal/gateway/config"
 	"github.com/ciameksw/mood-api/gateway/internal/gateway/httpclient"
+	"github.com/ciameksw/mood-api/pkg/localtime"
 )
 
 type QuoteService struct {
@@ -24,6 +25,8 @@ func (qs *QuoteService) GetTodayQuote(r *http.Request) (*http.Response, error) {
 		Method:      r.Method,
 		Body:        r.Body,
 		ContentType: &ct,
+		// The quote of the day changes at midnight in the user's time zone
+		Headers: localtime.Headers(r),
 	}
 	resp, err := httpclient.SendRequest(params)
 	if err != nil {
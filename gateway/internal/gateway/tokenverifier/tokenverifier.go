@@ -0,0 +1,276 @@
+package tokenverifier
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ciameksw/mood-api/gateway/internal/gateway/config"
+	"github.com/ciameksw/mood-api/gateway/internal/gateway/httpclient"
+	"github.com/ciameksw/mood-api/pkg/jwks"
+	"github.com/ciameksw/mood-api/pkg/logger"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	// ErrUnknownKey means the token was signed with a key we do not know even after refreshing the JWKS
+	ErrUnknownKey = errors.New("unknown signing key")
+	// ErrStaleRevocations means the revocation list could not be synced recently enough to be trusted
+	ErrStaleRevocations = errors.New("revocation list is stale")
+	// ErrRevoked means the token was revoked before its expiry
+	ErrRevoked = errors.New("token has been revoked")
+)
+
+const (
+	// Minimum time between JWKS fetches triggered by unknown kids
+	minKeysRefreshInterval = 30 * time.Second
+	// Revocations are fetched with an overlap so entries committed out of order are not missed
+	revocationSyncOverlap = time.Minute
+	// Number of missed syncs after which the revocation list is no longer trusted
+	maxMissedRevocationSyncs = 3
+)
+
+// Claims mirrors the access token claims issued by the auth service
+type Claims struct {
+	UserID    int
+	Roles     []string `json:"roles,omitempty"`
+	Timezone  string   `json:"tz,omitempty"`
+	WeekStart string   `json:"wks,omitempty"`
+	Purpose   string   `json:"purpose,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// TokenVerifier checks access tokens locally against keys and revocations synced from the auth service
+type TokenVerifier struct {
+	AuthURL                string
+	KeysRefreshInterval    time.Duration
+	RevocationSyncInterval time.Duration
+	Logger                 *logger.Logger
+
+	mu              sync.RWMutex
+	keys            map[string]crypto.PublicKey
+	keysFetchedAt   time.Time
+	revoked         map[string]time.Time
+	revocationsAsOf time.Time
+	revocationsAt   time.Time
+
+	refreshMu sync.Mutex
+}
+
+func NewTokenVerifier(cfg *config.Config, log *logger.Logger) *TokenVerifier {
+	return &TokenVerifier{
+		AuthURL:                cfg.AuthURL,
+		KeysRefreshInterval:    cfg.AuthKeysRefreshInterval,
+		RevocationSyncInterval: cfg.AuthRevocationSyncInterval,
+		Logger:                 log,
+		keys:                   make(map[string]crypto.PublicKey),
+		revoked:                make(map[string]time.Time),
+	}
+}
+
+// Start syncs keys and revocations in the background until ctx is cancelled
+func (tv *TokenVerifier) Start(ctx context.Context) {
+	go tv.runPeriodically(ctx, tv.KeysRefreshInterval, func() error {
+		return tv.refreshKeys()
+	})
+	go tv.runPeriodically(ctx, tv.RevocationSyncInterval, func() error {
+		return tv.syncRevocations()
+	})
+}
+
+// Verify validates the signature, expiry and revocation status of a token
+func (tv *TokenVerifier) Verify(tokenString string) (*Claims, error) {
+	if !tv.revocationsFresh() {
+		return nil, ErrStaleRevocations
+	}
+
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, tv.keyFunc, jwt.WithValidMethods([]string{"RS256", "EdDSA"}))
+	if err != nil {
+		if errors.Is(err, ErrUnknownKey) {
+			return nil, ErrUnknownKey
+		}
+		return nil, err
+	}
+
+	if claims.ID == "" || claims.UserID == 0 {
+		return nil, errors.New("token is missing required claims")
+	}
+
+	// Challenge tokens from a two-factor login are signed with the same keys but are not access tokens
+	if claims.Purpose != "" {
+		return nil, errors.New("not an access token")
+	}
+
+	tv.mu.RLock()
+	_, revoked := tv.revoked[claims.ID]
+	tv.mu.RUnlock()
+	if revoked {
+		return nil, ErrRevoked
+	}
+
+	return claims, nil
+}
+
+func (tv *TokenVerifier) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		return nil, errors.New("token has no kid")
+	}
+
+	if key, ok := tv.getKey(kid); ok {
+		return key, nil
+	}
+
+	// The key may have been rotated in since the last refresh
+	if err := tv.refreshKeysThrottled(); err != nil {
+		tv.Logger.Error.Printf("Failed to refresh signing keys: %v", err)
+	}
+
+	if key, ok := tv.getKey(kid); ok {
+		return key, nil
+	}
+	return nil, ErrUnknownKey
+}
+
+func (tv *TokenVerifier) getKey(kid string) (crypto.PublicKey, bool) {
+	tv.mu.RLock()
+	defer tv.mu.RUnlock()
+	key, ok := tv.keys[kid]
+	return key, ok
+}
+
+func (tv *TokenVerifier) revocationsFresh() bool {
+	tv.mu.RLock()
+	defer tv.mu.RUnlock()
+	if tv.revocationsAt.IsZero() {
+		return false
+	}
+	return time.Since(tv.revocationsAt) < maxMissedRevocationSyncs*tv.RevocationSyncInterval
+}
+
+func (tv *TokenVerifier) refreshKeysThrottled() error {
+	tv.refreshMu.Lock()
+	defer tv.refreshMu.Unlock()
+
+	tv.mu.RLock()
+	recent := time.Since(tv.keysFetchedAt) < minKeysRefreshInterval
+	tv.mu.RUnlock()
+	if recent {
+		return nil
+	}
+
+	return tv.fetchKeys()
+}
+
+func (tv *TokenVerifier) refreshKeys() error {
+	tv.refreshMu.Lock()
+	defer tv.refreshMu.Unlock()
+	return tv.fetchKeys()
+}
+
+func (tv *TokenVerifier) fetchKeys() error {
+	var set jwks.Set
+	if err := tv.getJSON(tv.AuthURL+"/auth/.well-known/jwks.json", &set); err != nil {
+		return err
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		pub, err := k.PublicKey()
+		if err != nil {
+			tv.Logger.Error.Printf("Skipping signing key %s: %v", k.Kid, err)
+			continue
+		}
+		keys[k.Kid] = pub
+	}
+
+	tv.mu.Lock()
+	tv.keys = keys
+	tv.keysFetchedAt = time.Now()
+	tv.mu.Unlock()
+	return nil
+}
+
+func (tv *TokenVerifier) syncRevocations() error {
+	tv.mu.RLock()
+	since := tv.revocationsAsOf
+	tv.mu.RUnlock()
+
+	u := tv.AuthURL + "/auth/revocations"
+	if !since.IsZero() {
+		q := url.Values{}
+		q.Set("since", since.Add(-revocationSyncOverlap).Format(time.RFC3339Nano))
+		u += "?" + q.Encode()
+	}
+
+	var body struct {
+		AsOf    time.Time `json:"asOf"`
+		Revoked []struct {
+			JTI       string    `json:"jti"`
+			ExpiresAt time.Time `json:"expiresAt"`
+		} `json:"revoked"`
+	}
+	if err := tv.getJSON(u, &body); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	tv.mu.Lock()
+	defer tv.mu.Unlock()
+
+	for _, r := range body.Revoked {
+		tv.revoked[r.JTI] = r.ExpiresAt
+	}
+	// Expired tokens fail validation anyway, so they no longer need to be remembered
+	for jti, expiresAt := range tv.revoked {
+		if expiresAt.Before(now) {
+			delete(tv.revoked, jti)
+		}
+	}
+	tv.revocationsAsOf = body.AsOf
+	tv.revocationsAt = now
+	return nil
+}
+
+func (tv *TokenVerifier) getJSON(u string, v interface{}) error {
+	resp, err := httpclient.SendRequest(httpclient.RequestParams{
+		URL:    u,
+		Method: http.MethodGet,
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s from %s", resp.Status, strings.SplitN(u, "?", 2)[0])
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// Helper function to run a sync on a fixed interval
+func (tv *TokenVerifier) runPeriodically(ctx context.Context, interval time.Duration, sync func() error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := sync(); err != nil {
+			tv.Logger.Error.Printf("Token verifier sync failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
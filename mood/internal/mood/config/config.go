@@ -11,6 +11,8 @@ type Config struct {
 	PostgresPassword string
 	PostgresDatabase string
 	PostgresSSLMode  string
+
+	SummaryRollup string
 }
 
 func GetConfig() *Config {
@@ -23,5 +25,7 @@ func GetConfig() *Config {
 		PostgresPassword: configutil.GetEnv("POSTGRES_PASSWORD", "password"),
 		PostgresDatabase: configutil.GetEnv("POSTGRES_DATABASE", "mood_api_db"),
 		PostgresSSLMode:  configutil.GetEnv("POSTGRES_SSLMODE", "disable"),
+
+		SummaryRollup: configutil.GetEnv("MOOD_SUMMARY_ROLLUP", "dominant"),
 	}
 }
@@ -3,10 +3,12 @@ package repository
 import (
 	"database/sql"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/ciameksw/mood-api/pkg/postgres"
 	"github.com/ciameksw/mood-api/pkg/queryutil"
+	"github.com/lib/pq"
 )
 
 type DBOperations struct {
@@ -14,15 +16,17 @@ type DBOperations struct {
 }
 
 type MoodType struct {
-	ID          int    `json:"id"`
-	Name        string `json:"name"`
-	Description string `json:"description"`
+	ID          int      `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Valence     *float64 `json:"valence"` // -1 unpleasant to 1 pleasant, nil when not placed on the circumplex
+	Arousal     *float64 `json:"arousal"` // -1 deactivated to 1 activated
 }
 
 // GetMoodTypes retrieves all mood types from the database
 func (o *DBOperations) GetMoodTypes() ([]MoodType, error) {
 	moodTypes := make([]MoodType, 0)
-	query := "SELECT id, name, description FROM mood_type"
+	query := "SELECT id, name, description, valence, arousal FROM mood_type"
 
 	rows, err := o.Postgres.DB.Query(query)
 	if err != nil {
@@ -32,7 +36,7 @@ func (o *DBOperations) GetMoodTypes() ([]MoodType, error) {
 
 	for rows.Next() {
 		var mt MoodType
-		if err := rows.Scan(&mt.ID, &mt.Name, &mt.Description); err != nil {
+		if err := rows.Scan(&mt.ID, &mt.Name, &mt.Description, &mt.Valence, &mt.Arousal); err != nil {
 			return nil, err
 		}
 		moodTypes = append(moodTypes, mt)
@@ -45,50 +49,196 @@ func (o *DBOperations) GetMoodTypes() ([]MoodType, error) {
 	return moodTypes, nil
 }
 
-// AddMoodEntry inserts a new mood entry into the database
-func (o *DBOperations) AddMoodEntry(userId int, moodDate string, moodTypeID int, note string) (int, error) {
-	var entryID int
-	query := "INSERT INTO mood (user_id, mood_date, mood_type_id, note, created_at) VALUES ($1, $2, $3, $4, $5) RETURNING id"
+// AddMoodType inserts a new mood type into the database
+func (o *DBOperations) AddMoodType(name, description string, valence, arousal *float64) (*MoodType, error) {
+	mt := MoodType{Name: name, Description: description, Valence: valence, Arousal: arousal}
+	query := "INSERT INTO mood_type (name, description, valence, arousal) VALUES ($1, $2, $3, $4) RETURNING id"
 
-	err := o.Postgres.DB.QueryRow(query, userId, moodDate, moodTypeID, note, time.Now()).Scan(&entryID)
+	err := o.Postgres.DB.QueryRow(query, name, description, valence, arousal).Scan(&mt.ID)
 	if err != nil {
-		return 0, err
+		if isPQError(err, "23505") {
+			return nil, errors.New("mood type already exists")
+		}
+		return nil, err
 	}
 
-	return entryID, nil
+	return &mt, nil
 }
 
-// GetMoodEntryByDateAndUser retrieves a mood entry for a specific user on a specific date
-func (o *DBOperations) GetMoodEntryByDateAndUser(userId int, moodDate string) (*MoodEntry, error) {
-	var me MoodEntry
-	query := "SELECT id, user_id, mood_date, mood_type_id, note, created_at FROM mood WHERE user_id = $1 AND mood_date = $2"
+// UpdateMoodType updates the name, description and circumplex position of a mood type
+func (o *DBOperations) UpdateMoodType(id int, name, description string, valence, arousal *float64) error {
+	query := "UPDATE mood_type SET name = $1, description = $2, valence = $3, arousal = $4 WHERE id = $5"
 
-	err := o.Postgres.DB.QueryRow(query, userId, moodDate).Scan(&me.ID, &me.UserID, &me.MoodDate, &me.MoodTypeID, &me.Note, &me.CreatedAt)
+	result, err := o.Postgres.DB.Exec(query, name, description, valence, arousal, id)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, errors.New("user not found")
+		if isPQError(err, "23505") {
+			return errors.New("mood type already exists")
 		}
-		return nil, err
+		return err
 	}
 
-	return &me, nil
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return errors.New("mood type not found")
+	}
+
+	return nil
+}
+
+// DeleteMoodType deletes a mood type that is not referenced by any mood entry or advice mapping
+func (o *DBOperations) DeleteMoodType(id int) error {
+	query := "DELETE FROM mood_type WHERE id = $1"
+
+	result, err := o.Postgres.DB.Exec(query, id)
+	if err != nil {
+		if isPQError(err, "23503") {
+			return errors.New("mood type in use")
+		}
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return errors.New("mood type not found")
+	}
+
+	return nil
+}
+
+// Helper function to check for a Postgres error code, 23505 is a unique violation and 23503 a foreign key violation
+func isPQError(err error, code pq.ErrorCode) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == code
+}
+
+// AddMoodEntry inserts a new mood check-in with its tags, moodDate is the date of loggedAt in the user's time zone
+func (o *DBOperations) AddMoodEntry(userId int, moodDate string, loggedAt time.Time, timezone string, moodTypeID int, intensity *int, note string, tags []string) (int, error) {
+	tx, err := o.Postgres.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var entryID int
+	query := "INSERT INTO mood (user_id, mood_date, logged_at, timezone, mood_type_id, intensity, note, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id"
+
+	err = tx.QueryRow(query, userId, moodDate, loggedAt, timezone, moodTypeID, intensity, note, time.Now()).Scan(&entryID)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := setEntryTags(tx, userId, entryID, tags); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return entryID, nil
 }
 
 type MoodEntry struct {
 	ID         int       `json:"id"`
 	UserID     int       `json:"userId"`
 	MoodDate   string    `json:"moodDate"`
+	LoggedAt   time.Time `json:"loggedAt"`
+	Timezone   string    `json:"timezone"`
 	MoodTypeID int       `json:"moodTypeId"`
+	Intensity  *int      `json:"intensity"` // 1 to 10, nil when not given
 	Note       string    `json:"note"`
+	Tags       []string  `json:"tags"`
 	CreatedAt  time.Time `json:"createdAt"`
 }
 
-// GetMoodEntries retrieves mood entries for a user within a date range
-func (o *DBOperations) GetMoodEntries(input queryutil.GetParams) ([]MoodEntry, error) {
-	moodEntries := make([]MoodEntry, 0)
-	query := "SELECT id, user_id, mood_date, mood_type_id, note, created_at FROM mood WHERE user_id = $1 AND mood_date BETWEEN $2 AND $3"
+// entryTagsColumn selects the tag names of the mood row m, sorted
+const entryTagsColumn = `COALESCE((
+		SELECT array_agg(t.name ORDER BY t.name) FROM mood_entry_tag et JOIN mood_tag t ON t.id = et.tag_id WHERE et.mood_id = m.id
+	), '{}')`
 
-	rows, err := o.Postgres.DB.Query(query, input.UserID, input.StartDate, input.EndDate)
+// Helper function to show the check-in time in the time zone it was logged in
+func inTimezone(t time.Time, timezone string) time.Time {
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return t
+	}
+	return t.In(loc)
+}
+
+// Sort orders of mood entry listings, by check-in time or by when the entry was recorded
+const (
+	SortDate          = "date"
+	SortDateDesc      = "-date"
+	SortCreatedAt     = "createdAt"
+	SortCreatedAtDesc = "-createdAt"
+)
+
+// MoodSorts lists the valid sort orders, the default first
+var MoodSorts = []string{SortDate, SortDateDesc, SortCreatedAt, SortCreatedAtDesc}
+
+// moodSortColumns maps a sort order to its key column and whether it is descending, the entry id breaks ties
+var moodSortColumns = map[string]struct {
+	column string
+	desc   bool
+}{
+	SortDate:          {"m.logged_at", false},
+	SortDateDesc:      {"m.logged_at", true},
+	SortCreatedAt:     {"m.created_at", false},
+	SortCreatedAtDesc: {"m.created_at", true},
+}
+
+// GetMoodEntries retrieves a page of mood entries for a user within a date range, only those with every one of tags when given
+func (o *DBOperations) GetMoodEntries(input queryutil.GetParams, tags []string, page queryutil.PageParams) (*queryutil.Page[MoodEntry], error) {
+	sort, ok := moodSortColumns[page.Sort]
+	if !ok {
+		return nil, errors.New("unknown sort")
+	}
+	direction, comparison := "", ">"
+	if sort.desc {
+		direction, comparison = " DESC", "<"
+	}
+
+	if tags == nil {
+		tags = []string{}
+	}
+	args := []interface{}{input.UserID, input.StartDate, input.EndDate, pq.Array(tags)}
+
+	keyset := ""
+	if page.Cursor != nil {
+		key, err := time.Parse(time.RFC3339Nano, page.Cursor.Key)
+		if err != nil {
+			return nil, errors.New("invalid cursor")
+		}
+		// created_at has no time zone, the key is compared as it was read
+		if sort.column == "m.created_at" {
+			args = append(args, key.Format("2006-01-02T15:04:05.999999"), page.Cursor.ID)
+		} else {
+			args = append(args, key, page.Cursor.ID)
+		}
+		keyset = fmt.Sprintf("AND (%s, m.id) %s ($5, $6)", sort.column, comparison)
+	}
+	args = append(args, page.Limit+1)
+
+	moodEntries := make([]MoodEntry, 0)
+	query := `
+		SELECT m.id, m.user_id, m.mood_date, m.logged_at, m.timezone, m.mood_type_id, m.intensity, m.note, ` + entryTagsColumn + `, m.created_at
+		FROM mood m
+		WHERE m.user_id = $1 AND m.mood_date BETWEEN $2 AND $3
+			AND (
+				SELECT COUNT(*) FROM mood_entry_tag et JOIN mood_tag t ON t.id = et.tag_id
+				WHERE et.mood_id = m.id AND t.name = ANY($4::text[])
+			) = cardinality($4::text[])
+			` + keyset + `
+		ORDER BY ` + sort.column + direction + `, m.id` + direction + `
+		LIMIT $` + fmt.Sprint(len(args))
+
+	rows, err := o.Postgres.DB.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -96,9 +246,10 @@ func (o *DBOperations) GetMoodEntries(input queryutil.GetParams) ([]MoodEntry, e
 
 	for rows.Next() {
 		var me MoodEntry
-		if err := rows.Scan(&me.ID, &me.UserID, &me.MoodDate, &me.MoodTypeID, &me.Note, &me.CreatedAt); err != nil {
+		if err := rows.Scan(&me.ID, &me.UserID, &me.MoodDate, &me.LoggedAt, &me.Timezone, &me.MoodTypeID, &me.Intensity, &me.Note, pq.Array(&me.Tags), &me.CreatedAt); err != nil {
 			return nil, err
 		}
+		me.LoggedAt = inTimezone(me.LoggedAt, me.Timezone)
 		moodEntries = append(moodEntries, me)
 	}
 
@@ -106,28 +257,115 @@ func (o *DBOperations) GetMoodEntries(input queryutil.GetParams) ([]MoodEntry, e
 		return nil, err
 	}
 
-	return moodEntries, nil
+	result := queryutil.NewPage(moodEntries, page.Limit, func(last MoodEntry) queryutil.Cursor {
+		key := last.LoggedAt
+		if sort.column == "m.created_at" {
+			key = last.CreatedAt
+		}
+		return queryutil.Cursor{
+			Sort:      page.Sort,
+			Key:       key.Format(time.RFC3339Nano),
+			ID:        last.ID,
+			StartDate: input.StartDate,
+			EndDate:   input.EndDate,
+		}
+	})
+	return &result, nil
 }
 
 type MoodSummary struct {
-	MoodTypeID int     `json:"moodTypeId"`
-	Count      int     `json:"count"`
-	Percentage float64 `json:"percentage"`
+	Moods []MoodTypeSummary `json:"moods"`
+	// Averages over the counted check-ins, nil when none of them has the value
+	AverageIntensity *float64 `json:"averageIntensity"`
+	Valence          *float64 `json:"valence"`
+	Arousal          *float64 `json:"arousal"`
+	// Tags is the mood distribution of the check-ins with each tag, only filled when asked for
+	Tags []TagSummary `json:"tags,omitempty"`
 }
 
-// GetMoodSummary retrieves a summary of mood entries for a user within a date range
-func (o *DBOperations) GetMoodSummary(input queryutil.GetParams) ([]MoodSummary, error) {
-	summary := make([]MoodSummary, 0)
-	query := `
-        SELECT 
-            mood_type_id, 
-            COUNT(*) as count,
-            ROUND(100.0 * COUNT(*) / SUM(COUNT(*)) OVER (), 2) as percentage
-        FROM mood
-        WHERE user_id = $1 AND mood_date BETWEEN $2 AND $3 
-        GROUP BY mood_type_id
+type MoodTypeSummary struct {
+	MoodTypeID         int      `json:"moodTypeId"`
+	Count              int      `json:"count"`
+	Percentage         float64  `json:"percentage"`
+	WeightedPercentage float64  `json:"weightedPercentage"`
+	AverageIntensity   *float64 `json:"averageIntensity"`
+}
+
+type TagSummary struct {
+	Tag   string            `json:"tag"`
+	Moods []MoodTypeSummary `json:"moods"`
+}
+
+// Daily rollups decide which check-ins count in a mood summary
+const (
+	RollupAll      = "all"      // every check-in
+	RollupLatest   = "latest"   // the last check-in of each day
+	RollupFirst    = "first"    // the first check-in of each day
+	RollupDominant = "dominant" // the most frequent mood of each day, the later one on a tie
+)
+
+// Rollups lists the valid daily rollups
+var Rollups = []string{RollupAll, RollupLatest, RollupFirst, RollupDominant}
+
+// rollupQueries pick the counted check-ins out of the source rows, %[1]s holds columns that split each day further
+var rollupQueries = map[string]string{
+	RollupAll: `
+		SELECT %[1]s mood_type_id, intensity FROM source`,
+	RollupLatest: `
+		SELECT DISTINCT ON (%[1]s mood_date) %[1]s mood_type_id, intensity FROM source
+		ORDER BY %[1]s mood_date, logged_at DESC, id DESC`,
+	RollupFirst: `
+		SELECT DISTINCT ON (%[1]s mood_date) %[1]s mood_type_id, intensity FROM source
+		ORDER BY %[1]s mood_date, logged_at, id`,
+	RollupDominant: `
+		SELECT DISTINCT ON (%[1]s mood_date) %[1]s mood_type_id, AVG(intensity) AS intensity FROM source
+		GROUP BY %[1]s mood_date, mood_type_id
+		ORDER BY %[1]s mood_date, COUNT(*) DESC, MAX(logged_at) DESC`,
+}
+
+// Check-ins of the user in the date range, and the same once per tag they carry
+const (
+	moodSource = `
+		SELECT id, mood_date, logged_at, mood_type_id, intensity FROM mood
+		WHERE user_id = $1 AND mood_date BETWEEN $2 AND $3`
+	taggedMoodSource = `
+		SELECT m.id, m.mood_date, m.logged_at, m.mood_type_id, m.intensity, t.name AS tag FROM mood m
+		JOIN mood_entry_tag et ON et.mood_id = m.id
+		JOIN mood_tag t ON t.id = et.tag_id
+		WHERE m.user_id = $1 AND m.mood_date BETWEEN $2 AND $3`
+)
+
+// Check-ins without an intensity weigh as much as the middle of the 1 to 10 scale
+const defaultIntensityWeight = "5.5"
+
+// Helper function to build the source and daily common table expressions of a summary query
+func dailyCTE(rollup, source, partition string) (string, error) {
+	daily, ok := rollupQueries[rollup]
+	if !ok {
+		return "", errors.New("unknown rollup")
+	}
+	return "WITH source AS (" + source + "\n\t\t), daily AS (" + fmt.Sprintf(daily, partition) + "\n\t\t)", nil
+}
+
+// GetMoodSummary retrieves a summary of mood entries for a user within a date range, counting the check-ins picked by rollup
+func (o *DBOperations) GetMoodSummary(input queryutil.GetParams, rollup string, byTag bool) (*MoodSummary, error) {
+	cte, err := dailyCTE(rollup, moodSource, "")
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &MoodSummary{Moods: make([]MoodTypeSummary, 0)}
+	query := cte + `
+		SELECT 
+			mood_type_id, 
+			COUNT(*) as count,
+			ROUND(100.0 * COUNT(*) / SUM(COUNT(*)) OVER (), 2) as percentage,
+			ROUND(100.0 * SUM(COALESCE(intensity, ` + defaultIntensityWeight + `)) / SUM(SUM(COALESCE(intensity, ` + defaultIntensityWeight + `))) OVER (), 2) as weighted_percentage,
+			ROUND(AVG(intensity), 2) as average_intensity
+		FROM daily
+		GROUP BY mood_type_id
 		ORDER BY count DESC
-    `
+	`
 
 	rows, err := o.Postgres.DB.Query(query, input.UserID, input.StartDate, input.EndDate)
 	if err != nil {
@@ -136,38 +374,114 @@ func (o *DBOperations) GetMoodSummary(input queryutil.GetParams) ([]MoodSummary,
 	defer rows.Close()
 
 	for rows.Next() {
-		var ms MoodSummary
-		if err := rows.Scan(&ms.MoodTypeID, &ms.Count, &ms.Percentage); err != nil {
+		var ms MoodTypeSummary
+		if err := rows.Scan(&ms.MoodTypeID, &ms.Count, &ms.Percentage, &ms.WeightedPercentage, &ms.AverageIntensity); err != nil {
 			return nil, err
 		}
-		summary = append(summary, ms)
+		summary.Moods = append(summary.Moods, ms)
 	}
 
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
 
+	// The circumplex position is the intensity-weighted mean of the mood types' coordinates
+	query = cte + `
+		SELECT
+			ROUND(AVG(d.intensity), 2),
+			ROUND(SUM(mt.valence * COALESCE(d.intensity, ` + defaultIntensityWeight + `)) / NULLIF(SUM(COALESCE(d.intensity, ` + defaultIntensityWeight + `)) FILTER (WHERE mt.valence IS NOT NULL), 0), 2),
+			ROUND(SUM(mt.arousal * COALESCE(d.intensity, ` + defaultIntensityWeight + `)) / NULLIF(SUM(COALESCE(d.intensity, ` + defaultIntensityWeight + `)) FILTER (WHERE mt.arousal IS NOT NULL), 0), 2)
+		FROM daily d
+		LEFT JOIN mood_type mt ON mt.id = d.mood_type_id
+	`
+
+	err = o.Postgres.DB.QueryRow(query, input.UserID, input.StartDate, input.EndDate).Scan(&summary.AverageIntensity, &summary.Valence, &summary.Arousal)
+	if err != nil {
+		return nil, err
+	}
+
+	if byTag {
+		summary.Tags, err = o.getTagSummaries(input, rollup)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return summary, nil
 }
 
-// UpdateMoodEntry updates an existing mood entry in the database
-func (o *DBOperations) UpdateMoodEntry(entryID int, moodTypeID int, note string) error {
-	query := "UPDATE mood SET mood_type_id = $1, note = $2 WHERE id = $3"
+// Helper function to summarize the check-ins of each tag separately, the rollup picks per tag and day
+func (o *DBOperations) getTagSummaries(input queryutil.GetParams, rollup string) ([]TagSummary, error) {
+	cte, err := dailyCTE(rollup, taggedMoodSource, "tag,")
+	if err != nil {
+		return nil, err
+	}
 
-	result, err := o.Postgres.DB.Exec(query, moodTypeID, note, entryID)
+	tags := make([]TagSummary, 0)
+	query := cte + `
+		SELECT
+			tag,
+			mood_type_id,
+			COUNT(*) as count,
+			ROUND(100.0 * COUNT(*) / SUM(COUNT(*)) OVER (PARTITION BY tag), 2) as percentage,
+			ROUND(100.0 * SUM(COALESCE(intensity, ` + defaultIntensityWeight + `)) / SUM(SUM(COALESCE(intensity, ` + defaultIntensityWeight + `))) OVER (PARTITION BY tag), 2) as weighted_percentage,
+			ROUND(AVG(intensity), 2) as average_intensity
+		FROM daily
+		GROUP BY tag, mood_type_id
+		ORDER BY tag, count DESC
+	`
+
+	rows, err := o.Postgres.DB.Query(query, input.UserID, input.StartDate, input.EndDate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var tag string
+		var ms MoodTypeSummary
+		if err := rows.Scan(&tag, &ms.MoodTypeID, &ms.Count, &ms.Percentage, &ms.WeightedPercentage, &ms.AverageIntensity); err != nil {
+			return nil, err
+		}
+		if len(tags) == 0 || tags[len(tags)-1].Tag != tag {
+			tags = append(tags, TagSummary{Tag: tag, Moods: make([]MoodTypeSummary, 0)})
+		}
+		tags[len(tags)-1].Moods = append(tags[len(tags)-1].Moods, ms)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+// UpdateMoodEntry updates an existing mood entry in the database, its tags are replaced unless tags is nil
+func (o *DBOperations) UpdateMoodEntry(entryID int, moodTypeID int, intensity *int, note string, tags []string) error {
+	tx, err := o.Postgres.DB.Begin()
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
-	rowsAffected, err := result.RowsAffected()
+	var userID int
+	query := "UPDATE mood SET mood_type_id = $1, intensity = $2, note = $3 WHERE id = $4 RETURNING user_id"
+
+	err = tx.QueryRow(query, moodTypeID, intensity, note, entryID).Scan(&userID)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errors.New("no rows updated")
+		}
 		return err
 	}
-	if rowsAffected == 0 {
-		return errors.New("no rows updated")
+
+	if tags != nil {
+		if err := setEntryTags(tx, userID, entryID, tags); err != nil {
+			return err
+		}
 	}
 
-	return nil
+	return tx.Commit()
 }
 
 // DeleteMoodEntry deletes a mood entry from the database
@@ -190,18 +504,87 @@ func (o *DBOperations) DeleteMoodEntry(entryID int) error {
 	return nil
 }
 
+// DeleteUserMoodEntries deletes every mood entry and tag of a user and returns how many entries there were
+func (o *DBOperations) DeleteUserMoodEntries(userID int) (int64, error) {
+	tx, err := o.Postgres.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("DELETE FROM mood WHERE user_id = $1", userID)
+	if err != nil {
+		return 0, err
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec("DELETE FROM mood_tag WHERE user_id = $1", userID)
+	if err != nil {
+		return 0, err
+	}
+
+	return deleted, tx.Commit()
+}
+
 // GetMoodEntryByID retrieves a mood entry by its ID
 func (o *DBOperations) GetMoodEntryByID(entryID int) (*MoodEntry, error) {
 	var me MoodEntry
-	query := "SELECT id, user_id, mood_date, mood_type_id, note, created_at FROM mood WHERE id = $1"
+	query := "SELECT m.id, m.user_id, m.mood_date, m.logged_at, m.timezone, m.mood_type_id, m.intensity, m.note, " + entryTagsColumn + ", m.created_at FROM mood m WHERE m.id = $1"
 
-	err := o.Postgres.DB.QueryRow(query, entryID).Scan(&me.ID, &me.UserID, &me.MoodDate, &me.MoodTypeID, &me.Note, &me.CreatedAt)
+	err := o.Postgres.DB.QueryRow(query, entryID).Scan(&me.ID, &me.UserID, &me.MoodDate, &me.LoggedAt, &me.Timezone, &me.MoodTypeID, &me.Intensity, &me.Note, pq.Array(&me.Tags), &me.CreatedAt)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, errors.New("mood entry not found")
 		}
 		return nil, err
 	}
+	me.LoggedAt = inTimezone(me.LoggedAt, me.Timezone)
 
 	return &me, nil
 }
+
+type MoodExportEntry struct {
+	ID         int       `json:"id"`
+	MoodDate   string    `json:"moodDate"`
+	LoggedAt   time.Time `json:"loggedAt"`
+	Timezone   string    `json:"timezone"`
+	MoodTypeID int       `json:"moodTypeId"`
+	MoodType   string    `json:"moodType"`
+	Intensity  *int      `json:"intensity"`
+	Note       string    `json:"note"`
+	Tags       []string  `json:"tags"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// ForEachUserMoodEntry calls fn for every mood entry of a user, oldest first, without loading them all into memory
+func (o *DBOperations) ForEachUserMoodEntry(userID int, fn func(MoodExportEntry) error) error {
+	query := `
+		SELECT m.id, to_char(m.mood_date, 'YYYY-MM-DD'), m.logged_at, m.timezone, COALESCE(m.mood_type_id, 0), COALESCE(mt.name, ''), m.intensity, COALESCE(m.note, ''), ` + entryTagsColumn + `, m.created_at
+		FROM mood m
+		LEFT JOIN mood_type mt ON mt.id = m.mood_type_id
+		WHERE m.user_id = $1
+		ORDER BY m.logged_at, m.id
+	`
+
+	rows, err := o.Postgres.DB.Query(query, userID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var me MoodExportEntry
+		if err := rows.Scan(&me.ID, &me.MoodDate, &me.LoggedAt, &me.Timezone, &me.MoodTypeID, &me.MoodType, &me.Intensity, &me.Note, pq.Array(&me.Tags), &me.CreatedAt); err != nil {
+			return err
+		}
+		me.LoggedAt = inTimezone(me.LoggedAt, me.Timezone)
+		if err := fn(me); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"unicode"
+
+	"github.com/lib/pq"
+)
+
+// searchConfig is the text search configuration of the note_tsv column, queries have to use the same one
+const searchConfig = "english"
+
+// Matches are wrapped in mark tags, the note itself is HTML-escaped so the highlight can be shown as HTML
+const headlineOptions = "StartSel=<mark>, StopSel=</mark>, MinWords=10, MaxWords=30, MaxFragments=2, FragmentDelimiter=\" … \""
+
+type MoodSearchFilter struct {
+	UserID    int
+	Query     string
+	StartDate string // Both dates empty searches every entry
+	EndDate   string
+	Limit     int
+	Offset    int
+}
+
+type MoodSearchResult struct {
+	MoodEntry
+	Highlight string  `json:"highlight"` // Matching parts of the note, HTML-escaped with matches in <mark> tags
+	Rank      float64 `json:"rank"`
+}
+
+// SearchMoodEntries finds the mood entries of a user whose note matches the query, best matches first
+func (o *DBOperations) SearchMoodEntries(f MoodSearchFilter) ([]MoodSearchResult, error) {
+	tsquery := buildTSQuery(f.Query)
+	if tsquery == "" {
+		return nil, errors.New("empty search query")
+	}
+
+	results := make([]MoodSearchResult, 0)
+	query := `
+		WITH q AS (SELECT to_tsquery('` + searchConfig + `', $2) AS query)
+		SELECT m.id, m.user_id, m.mood_date, m.logged_at, m.timezone, m.mood_type_id, m.intensity, m.note, ` + entryTagsColumn + `, m.created_at,
+			ts_headline('` + searchConfig + `', replace(replace(replace(m.note, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), q.query, '` + headlineOptions + `'),
+			ts_rank(m.note_tsv, q.query) AS rank
+		FROM mood m, q
+		WHERE m.user_id = $1 AND m.note_tsv @@ q.query
+			AND ($3::date IS NULL OR m.mood_date BETWEEN $3::date AND $4::date)
+		ORDER BY rank DESC, m.logged_at DESC, m.id DESC
+		LIMIT $5 OFFSET $6
+	`
+
+	startDate := sql.NullString{String: f.StartDate, Valid: f.StartDate != ""}
+	endDate := sql.NullString{String: f.EndDate, Valid: f.EndDate != ""}
+	rows, err := o.Postgres.DB.Query(query, f.UserID, tsquery, startDate, endDate, f.Limit, f.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var sr MoodSearchResult
+		if err := rows.Scan(&sr.ID, &sr.UserID, &sr.MoodDate, &sr.LoggedAt, &sr.Timezone, &sr.MoodTypeID, &sr.Intensity, &sr.Note, pq.Array(&sr.Tags), &sr.CreatedAt, &sr.Highlight, &sr.Rank); err != nil {
+			return nil, err
+		}
+		sr.LoggedAt = inTimezone(sr.LoggedAt, sr.Timezone)
+		results = append(results, sr)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// Helper function to turn a search into a to_tsquery expression matching entries with every term.
+// "Quoted words" match as a phrase and a trailing * matches words starting with the term,
+// anything but letters and digits separates words so user input cannot break the expression.
+func buildTSQuery(search string) string {
+	terms := make([]string, 0)
+	for i, part := range strings.Split(search, `"`) {
+		// Every odd part was between quotes
+		if i%2 == 1 {
+			if phrase := tsPhrase(part, false); phrase != "" {
+				terms = append(terms, phrase)
+			}
+			continue
+		}
+		for _, word := range strings.Fields(part) {
+			if phrase := tsPhrase(strings.TrimSuffix(word, "*"), strings.HasSuffix(word, "*")); phrase != "" {
+				terms = append(terms, phrase)
+			}
+		}
+	}
+	return strings.Join(terms, " & ")
+}
+
+// Helper function to join the words of text as adjacent lexemes, the last one as a prefix when asked
+func tsPhrase(text string, prefix bool) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) == 0 {
+		return ""
+	}
+
+	for i, word := range words {
+		words[i] = "'" + word + "'"
+	}
+	if prefix {
+		words[len(words)-1] += ":*"
+	}
+	if len(words) == 1 {
+		return words[0]
+	}
+	return "(" + strings.Join(words, " <-> ") + ")"
+}
@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type Tag struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	Entries   int       `json:"entries"` // Number of mood entries with the tag
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// GetTags retrieves the tags of a user with how often each is used, by name
+func (o *DBOperations) GetTags(userID int) ([]Tag, error) {
+	tags := make([]Tag, 0)
+	query := `
+		SELECT t.id, t.name, COUNT(et.mood_id), t.created_at
+		FROM mood_tag t
+		LEFT JOIN mood_entry_tag et ON et.tag_id = t.id
+		WHERE t.user_id = $1
+		GROUP BY t.id
+		ORDER BY t.name
+	`
+
+	rows, err := o.Postgres.DB.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var t Tag
+		if err := rows.Scan(&t.ID, &t.Name, &t.Entries, &t.CreatedAt); err != nil {
+			return nil, err
+		}
+		tags = append(tags, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+// AddTag creates a tag for a user
+func (o *DBOperations) AddTag(userID int, name string) (*Tag, error) {
+	t := Tag{Name: name}
+	query := "INSERT INTO mood_tag (user_id, name, created_at) VALUES ($1, $2, $3) RETURNING id, created_at"
+
+	err := o.Postgres.DB.QueryRow(query, userID, name, time.Now()).Scan(&t.ID, &t.CreatedAt)
+	if err != nil {
+		if isPQError(err, "23505") {
+			return nil, errors.New("tag already exists")
+		}
+		return nil, err
+	}
+
+	return &t, nil
+}
+
+// RenameTag renames a tag of a user, the entries keep the tag
+func (o *DBOperations) RenameTag(userID, tagID int, name string) error {
+	query := "UPDATE mood_tag SET name = $1 WHERE id = $2 AND user_id = $3"
+
+	result, err := o.Postgres.DB.Exec(query, name, tagID, userID)
+	if err != nil {
+		if isPQError(err, "23505") {
+			return errors.New("tag already exists")
+		}
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return errors.New("tag not found")
+	}
+
+	return nil
+}
+
+// DeleteTag deletes a tag of a user and removes it from the entries
+func (o *DBOperations) DeleteTag(userID, tagID int) error {
+	query := "DELETE FROM mood_tag WHERE id = $1 AND user_id = $2"
+
+	result, err := o.Postgres.DB.Exec(query, tagID, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return errors.New("tag not found")
+	}
+
+	return nil
+}
+
+// Helper function to replace the tags of an entry, tags the user does not have yet are created
+func setEntryTags(tx *sql.Tx, userID, entryID int, names []string) error {
+	_, err := tx.Exec("DELETE FROM mood_entry_tag WHERE mood_id = $1", entryID)
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		return nil
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO mood_tag (user_id, name, created_at)
+		SELECT $1, name, $3 FROM unnest($2::text[]) AS name
+		ON CONFLICT (user_id, name) DO NOTHING
+	`, userID, pq.Array(names), time.Now())
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO mood_entry_tag (mood_id, tag_id)
+		SELECT $1, id FROM mood_tag WHERE user_id = $2 AND name = ANY($3)
+	`, entryID, userID, pq.Array(names))
+	return err
+}
@@ -3,17 +3,25 @@ package server
 import (
 	"encoding/json"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/ciameksw/mood-api/mood/internal/mood/repository"
 	"github.com/ciameksw/mood-api/pkg/httputil"
+	"github.com/ciameksw/mood-api/pkg/localtime"
 	"github.com/ciameksw/mood-api/pkg/queryutil"
 )
 
 type addMoodInput struct {
-	UserID     int    `json:"userId" validate:"required"`
-	MoodTypeID int    `json:"moodTypeId" validate:"required"`
-	Note       string `json:"note" validate:"max=500"`
-	Date       string `json:"date" validate:"required,datetime=2006-01-02"`
+	UserID     int      `json:"userId" validate:"required"`
+	MoodTypeID int      `json:"moodTypeId" validate:"required"`
+	Intensity  *int     `json:"intensity" validate:"omitnil,min=1,max=10"`
+	Note       string   `json:"note" validate:"max=500"`
+	Tags       []string `json:"tags" validate:"omitempty,max=10,dive,required,max=30"`
+	Date       string   `json:"date" validate:"omitempty,excluded_with=LoggedAt,datetime=2006-01-02"`
+	LoggedAt   string   `json:"loggedAt" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
 }
 
 func (s *Server) handleAddMood(w http.ResponseWriter, r *http.Request) {
@@ -25,25 +33,37 @@ func (s *Server) handleAddMood(w http.ResponseWriter, r *http.Request) {
 		httputil.HandleError(*s.Logger, w, "Invalid request payload", err, http.StatusBadRequest)
 		return
 	}
+	input.Tags = normalizeTags(input.Tags)
 
 	err = s.Validator.Struct(input)
 	if err != nil {
-		httputil.HandleError(*s.Logger, w, err.Error(), err, http.StatusBadRequest)
+		httputil.HandleValidationError(*s.Logger, w, err)
 		return
 	}
 
-	entry, err := s.DBOperations.GetMoodEntryByDateAndUser(input.UserID, input.Date)
-	if err != nil && err.Error() != "user not found" {
-		httputil.HandleError(*s.Logger, w, "Failed to check existing mood entry", err, http.StatusInternalServerError)
-		return
-	}
-
-	if entry != nil {
-		httputil.HandleError(*s.Logger, w, "Mood entry for this date already exists", nil, http.StatusConflict)
-		return
+	// Dates are calendar days in the user's time zone, not the database's
+	loc := localtime.Location(r)
+	now := time.Now().In(loc)
+	loggedAt := now
+	switch {
+	case input.LoggedAt != "":
+		loggedAt, err = time.Parse(time.RFC3339, input.LoggedAt)
+		if err != nil {
+			httputil.HandleError(*s.Logger, w, "Invalid loggedAt timestamp", err, http.StatusBadRequest)
+			return
+		}
+		loggedAt = loggedAt.In(loc)
+	case input.Date != "" && input.Date != now.Format("2006-01-02"):
+		// A check-in for another day without a time is placed at noon of that day
+		day, err := time.ParseInLocation("2006-01-02", input.Date, loc)
+		if err != nil {
+			httputil.HandleError(*s.Logger, w, "Invalid date", err, http.StatusBadRequest)
+			return
+		}
+		loggedAt = time.Date(day.Year(), day.Month(), day.Day(), 12, 0, 0, 0, loc)
 	}
 
-	_, err = s.DBOperations.AddMoodEntry(input.UserID, input.Date, input.MoodTypeID, input.Note)
+	_, err = s.DBOperations.AddMoodEntry(input.UserID, loggedAt.Format("2006-01-02"), loggedAt, loc.String(), input.MoodTypeID, input.Intensity, input.Note, input.Tags)
 	if err != nil {
 		httputil.HandleError(*s.Logger, w, "Failed to add mood entry", err, http.StatusInternalServerError)
 		return
@@ -64,17 +84,131 @@ func (s *Server) handleGetMoodTypes(w http.ResponseWriter, r *http.Request) {
 	httputil.WriteData(*s.Logger, w, moodTypes, http.StatusOK)
 }
 
+type moodTypeInput struct {
+	Name        string   `json:"name" validate:"required,max=50"`
+	Description string   `json:"description" validate:"max=500"`
+	Valence     *float64 `json:"valence" validate:"omitnil,min=-1,max=1"`
+	Arousal     *float64 `json:"arousal" validate:"omitnil,min=-1,max=1"`
+}
+
+func (s *Server) handleAddMoodType(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Adding mood type")
+	var input moodTypeInput
+
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Invalid request payload", err, http.StatusBadRequest)
+		return
+	}
+
+	err = s.Validator.Struct(input)
+	if err != nil {
+		httputil.HandleValidationError(*s.Logger, w, err)
+		return
+	}
+
+	moodType, err := s.DBOperations.AddMoodType(input.Name, input.Description, input.Valence, input.Arousal)
+	if err != nil {
+		if err.Error() == "mood type already exists" {
+			httputil.HandleError(*s.Logger, w, "Mood type with this name already exists", nil, http.StatusConflict)
+			return
+		}
+		httputil.HandleError(*s.Logger, w, "Failed to add mood type", err, http.StatusInternalServerError)
+		return
+	}
+
+	httputil.WriteData(*s.Logger, w, moodType, http.StatusCreated)
+}
+
+func (s *Server) handleUpdateMoodType(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Updating mood type")
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Invalid id parameter", err, http.StatusBadRequest)
+		return
+	}
+
+	var input moodTypeInput
+	err = json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Invalid request payload", err, http.StatusBadRequest)
+		return
+	}
+
+	err = s.Validator.Struct(input)
+	if err != nil {
+		httputil.HandleValidationError(*s.Logger, w, err)
+		return
+	}
+
+	err = s.DBOperations.UpdateMoodType(id, input.Name, input.Description, input.Valence, input.Arousal)
+	if err != nil {
+		if err.Error() == "mood type not found" {
+			httputil.HandleError(*s.Logger, w, "Mood type not found", nil, http.StatusNotFound)
+			return
+		}
+		if err.Error() == "mood type already exists" {
+			httputil.HandleError(*s.Logger, w, "Mood type with this name already exists", nil, http.StatusConflict)
+			return
+		}
+		httputil.HandleError(*s.Logger, w, "Failed to update mood type", err, http.StatusInternalServerError)
+		return
+	}
+
+	httputil.WriteSuccessMessage(*s.Logger, w, "Mood type updated", http.StatusOK)
+}
+
+func (s *Server) handleDeleteMoodType(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Deleting mood type")
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Invalid id parameter", err, http.StatusBadRequest)
+		return
+	}
+
+	err = s.DBOperations.DeleteMoodType(id)
+	if err != nil {
+		if err.Error() == "mood type not found" {
+			httputil.HandleError(*s.Logger, w, "Mood type not found", nil, http.StatusNotFound)
+			return
+		}
+		if err.Error() == "mood type in use" {
+			httputil.HandleError(*s.Logger, w, "Mood type is used by mood entries or advice mappings", nil, http.StatusConflict)
+			return
+		}
+		httputil.HandleError(*s.Logger, w, "Failed to delete mood type", err, http.StatusInternalServerError)
+		return
+	}
+
+	httputil.WriteSuccessMessage(*s.Logger, w, "Mood type deleted", http.StatusOK)
+}
+
+const (
+	defaultMoodsLimit = 50
+	maxMoodsLimit     = 200
+)
+
 func (s *Server) handleGetMoods(w http.ResponseWriter, r *http.Request) {
 	s.Logger.Info.Println("Getting moods")
 
-	input, err := queryutil.ParseTimeframeWithUserIDParams(r)
+	input, page, err := queryutil.ParsePagedTimeframeWithUserIDParams(r, repository.MoodSorts, defaultMoodsLimit, maxMoodsLimit)
 	if err != nil {
 		httputil.HandleError(*s.Logger, w, "Invalid query parameters", err, http.StatusBadRequest)
 		return
 	}
 
-	moods, err := s.DBOperations.GetMoodEntries(*input)
+	tags := normalizeTags(r.URL.Query()["tag"])
+	if slices.Contains(tags, "") {
+		httputil.WriteValidationErrors(*s.Logger, w, []httputil.FieldError{{Field: "tag", Message: "cannot be empty"}})
+		return
+	}
+
+	moods, err := s.DBOperations.GetMoodEntries(*input, tags, *page)
 	if err != nil {
+		if err.Error() == "invalid cursor" {
+			httputil.HandleError(*s.Logger, w, "Invalid query parameters", err, http.StatusBadRequest)
+			return
+		}
 		httputil.HandleError(*s.Logger, w, "Failed to retrieve moods", err, http.StatusInternalServerError)
 		return
 	}
@@ -82,6 +216,71 @@ func (s *Server) handleGetMoods(w http.ResponseWriter, r *http.Request) {
 	httputil.WriteData(*s.Logger, w, moods, http.StatusOK)
 }
 
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+func (s *Server) handleSearchMoods(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Searching moods")
+
+	q := r.URL.Query()
+	userID, err := strconv.Atoi(q.Get("userId"))
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Invalid userId parameter", err, http.StatusBadRequest)
+		return
+	}
+
+	filter := repository.MoodSearchFilter{
+		UserID: userID,
+		Query:  q.Get("q"),
+		Limit:  defaultSearchLimit,
+	}
+	fieldErrors := make([]httputil.FieldError, 0)
+	if strings.TrimSpace(filter.Query) == "" {
+		fieldErrors = append(fieldErrors, httputil.FieldError{Field: "q", Message: "is required"})
+	} else if len(filter.Query) > 200 {
+		fieldErrors = append(fieldErrors, httputil.FieldError{Field: "q", Message: "must be at most 200 characters"})
+	}
+	if v := q.Get("limit"); v != "" {
+		filter.Limit, err = strconv.Atoi(v)
+		if err != nil || filter.Limit < 1 || filter.Limit > maxSearchLimit {
+			fieldErrors = append(fieldErrors, httputil.FieldError{Field: "limit", Message: "must be between 1 and " + strconv.Itoa(maxSearchLimit)})
+		}
+	}
+	if v := q.Get("offset"); v != "" {
+		filter.Offset, err = strconv.Atoi(v)
+		if err != nil || filter.Offset < 0 {
+			fieldErrors = append(fieldErrors, httputil.FieldError{Field: "offset", Message: "must be a non-negative number"})
+		}
+	}
+	if len(fieldErrors) > 0 {
+		httputil.WriteValidationErrors(*s.Logger, w, fieldErrors)
+		return
+	}
+
+	// The date range is optional, without one every entry is searched
+	if q.Get("from") != "" || q.Get("to") != "" || q.Get("period") != "" {
+		filter.StartDate, filter.EndDate, err = queryutil.ParseTimeframeParams(r)
+		if err != nil {
+			httputil.HandleError(*s.Logger, w, "Invalid query parameters", err, http.StatusBadRequest)
+			return
+		}
+	}
+
+	results, err := s.DBOperations.SearchMoodEntries(filter)
+	if err != nil {
+		if err.Error() == "empty search query" {
+			httputil.WriteValidationErrors(*s.Logger, w, []httputil.FieldError{{Field: "q", Message: "must contain a word"}})
+			return
+		}
+		httputil.HandleError(*s.Logger, w, "Failed to search moods", err, http.StatusInternalServerError)
+		return
+	}
+
+	httputil.WriteData(*s.Logger, w, results, http.StatusOK)
+}
+
 func (s *Server) handleGetMoodSummary(w http.ResponseWriter, r *http.Request) {
 	s.Logger.Info.Println("Getting mood summary")
 
@@ -91,7 +290,25 @@ func (s *Server) handleGetMoodSummary(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	summary, err := s.DBOperations.GetMoodSummary(*input)
+	rollup := r.URL.Query().Get("rollup")
+	if rollup == "" {
+		rollup = s.Config.SummaryRollup
+	}
+	if !slices.Contains(repository.Rollups, rollup) {
+		httputil.WriteValidationErrors(*s.Logger, w, []httputil.FieldError{{Field: "rollup", Message: "must be one of: " + strings.Join(repository.Rollups, ", ")}})
+		return
+	}
+
+	byTag := false
+	if v := r.URL.Query().Get("byTag"); v != "" {
+		byTag, err = strconv.ParseBool(v)
+		if err != nil {
+			httputil.WriteValidationErrors(*s.Logger, w, []httputil.FieldError{{Field: "byTag", Message: "must be true or false"}})
+			return
+		}
+	}
+
+	summary, err := s.DBOperations.GetMoodSummary(*input, rollup, byTag)
 	if err != nil {
 		httputil.HandleError(*s.Logger, w, "Failed to retrieve mood summary", err, http.StatusInternalServerError)
 		return
@@ -103,7 +320,10 @@ func (s *Server) handleGetMoodSummary(w http.ResponseWriter, r *http.Request) {
 type updateMoodInput struct {
 	ID         int    `json:"id" validate:"required"`
 	MoodTypeID int    `json:"moodTypeId" validate:"required"`
+	Intensity  *int   `json:"intensity" validate:"omitnil,min=1,max=10"`
 	Note       string `json:"note" validate:"required,max=500"`
+	// Tags replace the entry's tags, leaving them out keeps the current ones
+	Tags []string `json:"tags" validate:"omitempty,max=10,dive,required,max=30"`
 }
 
 func (s *Server) handleUpdateMood(w http.ResponseWriter, r *http.Request) {
@@ -115,14 +335,15 @@ func (s *Server) handleUpdateMood(w http.ResponseWriter, r *http.Request) {
 		httputil.HandleError(*s.Logger, w, "Invalid request payload", err, http.StatusBadRequest)
 		return
 	}
+	input.Tags = normalizeTags(input.Tags)
 
 	err = s.Validator.Struct(input)
 	if err != nil {
-		httputil.HandleError(*s.Logger, w, err.Error(), err, http.StatusBadRequest)
+		httputil.HandleValidationError(*s.Logger, w, err)
 		return
 	}
 
-	err = s.DBOperations.UpdateMoodEntry(input.ID, input.MoodTypeID, input.Note)
+	err = s.DBOperations.UpdateMoodEntry(input.ID, input.MoodTypeID, input.Intensity, input.Note, input.Tags)
 	if err != nil {
 		httputil.HandleError(*s.Logger, w, "Failed to update mood entry", err, http.StatusInternalServerError)
 		return
@@ -158,6 +379,59 @@ func (s *Server) handleDeleteMood(w http.ResponseWriter, r *http.Request) {
 	httputil.WriteSuccessMessage(*s.Logger, w, "Mood entry deleted", http.StatusOK)
 }
 
+// handleDeleteUserMoods erases a user's mood history when their account is purged, it is not exposed by the gateway
+func (s *Server) handleDeleteUserMoods(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Deleting mood entries of user")
+
+	userID, err := strconv.Atoi(r.PathValue("userId"))
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Invalid userId parameter", err, http.StatusBadRequest)
+		return
+	}
+
+	deleted, err := s.DBOperations.DeleteUserMoodEntries(userID)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to delete mood entries", err, http.StatusInternalServerError)
+		return
+	}
+
+	s.Logger.Info.Printf("Deleted %d mood entries of user %d", deleted, userID)
+	httputil.WriteSuccessMessage(*s.Logger, w, "Mood entries of user deleted", http.StatusOK)
+}
+
+// handleExportUserMoods streams every mood entry of a user as NDJSON for the data export, it is not exposed by the gateway
+func (s *Server) handleExportUserMoods(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Exporting mood entries of user")
+
+	userID, err := strconv.Atoi(r.PathValue("userId"))
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Invalid userId parameter", err, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	enc := json.NewEncoder(w)
+	exported := 0
+	err = s.DBOperations.ForEachUserMoodEntry(userID, func(me repository.MoodExportEntry) error {
+		if err := enc.Encode(me); err != nil {
+			return err
+		}
+		exported++
+		return nil
+	})
+	if err != nil {
+		if exported == 0 {
+			httputil.HandleError(*s.Logger, w, "Failed to export mood entries", err, http.StatusInternalServerError)
+			return
+		}
+		// The status is already sent, aborting the connection keeps the caller from taking a partial export as complete
+		s.Logger.Error.Printf("Failed to export mood entries of user %d: %v", userID, err)
+		panic(http.ErrAbortHandler)
+	}
+
+	s.Logger.Info.Printf("Exported %d mood entries of user %d", exported, userID)
+}
+
 func (s *Server) handleGetMood(w http.ResponseWriter, r *http.Request) {
 	s.Logger.Info.Println("Getting mood entry by ID")
 	idStr := r.PathValue("id")
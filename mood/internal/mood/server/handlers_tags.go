@@ -0,0 +1,153 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ciameksw/mood-api/pkg/httputil"
+)
+
+func (s *Server) handleGetTags(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Getting tags")
+
+	userID, err := strconv.Atoi(r.URL.Query().Get("userId"))
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Invalid userId parameter", err, http.StatusBadRequest)
+		return
+	}
+
+	tags, err := s.DBOperations.GetTags(userID)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Failed to retrieve tags", err, http.StatusInternalServerError)
+		return
+	}
+
+	httputil.WriteData(*s.Logger, w, tags, http.StatusOK)
+}
+
+type tagInput struct {
+	UserID int    `json:"userId" validate:"required"`
+	Name   string `json:"name" validate:"required,max=30"`
+}
+
+func (s *Server) handleAddTag(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Adding tag")
+	var input tagInput
+
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Invalid request payload", err, http.StatusBadRequest)
+		return
+	}
+	input.Name = normalizeTag(input.Name)
+
+	err = s.Validator.Struct(input)
+	if err != nil {
+		httputil.HandleValidationError(*s.Logger, w, err)
+		return
+	}
+
+	tag, err := s.DBOperations.AddTag(input.UserID, input.Name)
+	if err != nil {
+		if err.Error() == "tag already exists" {
+			httputil.HandleError(*s.Logger, w, "Tag with this name already exists", nil, http.StatusConflict)
+			return
+		}
+		httputil.HandleError(*s.Logger, w, "Failed to add tag", err, http.StatusInternalServerError)
+		return
+	}
+
+	httputil.WriteData(*s.Logger, w, tag, http.StatusCreated)
+}
+
+func (s *Server) handleRenameTag(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Renaming tag")
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Invalid id parameter", err, http.StatusBadRequest)
+		return
+	}
+
+	var input tagInput
+	err = json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Invalid request payload", err, http.StatusBadRequest)
+		return
+	}
+	input.Name = normalizeTag(input.Name)
+
+	err = s.Validator.Struct(input)
+	if err != nil {
+		httputil.HandleValidationError(*s.Logger, w, err)
+		return
+	}
+
+	err = s.DBOperations.RenameTag(input.UserID, id, input.Name)
+	if err != nil {
+		if err.Error() == "tag not found" {
+			httputil.HandleError(*s.Logger, w, "Tag not found", nil, http.StatusNotFound)
+			return
+		}
+		if err.Error() == "tag already exists" {
+			httputil.HandleError(*s.Logger, w, "Tag with this name already exists", nil, http.StatusConflict)
+			return
+		}
+		httputil.HandleError(*s.Logger, w, "Failed to rename tag", err, http.StatusInternalServerError)
+		return
+	}
+
+	httputil.WriteSuccessMessage(*s.Logger, w, "Tag renamed", http.StatusOK)
+}
+
+func (s *Server) handleDeleteTag(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info.Println("Deleting tag")
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Invalid id parameter", err, http.StatusBadRequest)
+		return
+	}
+
+	userID, err := strconv.Atoi(r.URL.Query().Get("userId"))
+	if err != nil {
+		httputil.HandleError(*s.Logger, w, "Invalid userId parameter", err, http.StatusBadRequest)
+		return
+	}
+
+	err = s.DBOperations.DeleteTag(userID, id)
+	if err != nil {
+		if err.Error() == "tag not found" {
+			httputil.HandleError(*s.Logger, w, "Tag not found", nil, http.StatusNotFound)
+			return
+		}
+		httputil.HandleError(*s.Logger, w, "Failed to delete tag", err, http.StatusInternalServerError)
+		return
+	}
+
+	httputil.WriteSuccessMessage(*s.Logger, w, "Tag deleted", http.StatusOK)
+}
+
+// Tags are matched case-insensitively, so they are stored trimmed and in lower case
+func normalizeTag(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// Helper function to normalize a list of tags and drop duplicates, nil stays nil
+func normalizeTags(names []string) []string {
+	if names == nil {
+		return nil
+	}
+
+	tags := make([]string, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		tag := normalizeTag(name)
+		if seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	return tags
+}
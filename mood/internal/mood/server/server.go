@@ -6,6 +6,7 @@ import (
 
 	"github.com/ciameksw/mood-api/mood/internal/mood/config"
 	"github.com/ciameksw/mood-api/mood/internal/mood/repository"
+	"github.com/ciameksw/mood-api/pkg/httputil"
 	"github.com/ciameksw/mood-api/pkg/logger"
 	"github.com/ciameksw/mood-api/pkg/postgres"
 	"github.com/go-playground/validator/v10"
@@ -24,7 +25,7 @@ func NewServer(log *logger.Logger, cfg *config.Config, pg *postgres.PostgresDB)
 		Logger:       log,
 		Config:       cfg,
 		DBOperations: &repository.DBOperations{Postgres: pg},
-		Validator:    validator.New(),
+		Validator:    httputil.NewValidator(),
 	}
 }
 
@@ -34,10 +35,20 @@ func (s *Server) Start() error {
 	r.HandleFunc("POST /mood", s.handleAddMood)
 	r.HandleFunc("GET /mood", s.handleGetMoods)
 	r.HandleFunc("GET /mood/types", s.handleGetMoodTypes)
+	r.HandleFunc("POST /mood/types", s.handleAddMoodType)
+	r.HandleFunc("PUT /mood/types/{id}", s.handleUpdateMoodType)
+	r.HandleFunc("DELETE /mood/types/{id}", s.handleDeleteMoodType)
 	r.HandleFunc("GET /mood/summary", s.handleGetMoodSummary)
+	r.HandleFunc("GET /mood/search", s.handleSearchMoods)
+	r.HandleFunc("GET /mood/tags", s.handleGetTags)
+	r.HandleFunc("POST /mood/tags", s.handleAddTag)
+	r.HandleFunc("PUT /mood/tags/{id}", s.handleRenameTag)
+	r.HandleFunc("DELETE /mood/tags/{id}", s.handleDeleteTag)
 	r.HandleFunc("PUT /mood", s.handleUpdateMood)
 	r.HandleFunc("GET /mood/{id}", s.handleGetMood)
 	r.HandleFunc("DELETE /mood/{id}", s.handleDeleteMood)
+	r.HandleFunc("DELETE /mood/user/{userId}", s.handleDeleteUserMoods)
+	r.HandleFunc("GET /mood/user/{userId}/export", s.handleExportUserMoods)
 
 	r.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
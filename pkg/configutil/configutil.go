@@ -3,6 +3,8 @@ package configutil
 import (
 	"log"
 	"os"
+	"strconv"
+	"time"
 )
 
 func GetEnv(key, df string) string {
@@ -13,3 +15,51 @@ func GetEnv(key, df string) string {
 	}
 	return val
 }
+
+// GetEnvDuration reads a duration such as "720h" and falls back to df when missing or invalid
+func GetEnvDuration(key string, df time.Duration) time.Duration {
+	val, ok := os.LookupEnv(key)
+	if !ok {
+		log.Printf("Using default value for %s (%s)", key, df)
+		return df
+	}
+
+	d, err := time.ParseDuration(val)
+	if err != nil {
+		log.Printf("Invalid duration for %s (%s), using default value (%s)", key, val, df)
+		return df
+	}
+	return d
+}
+
+// GetEnvBool reads a boolean such as "true" or "0" and falls back to df when missing or invalid
+func GetEnvBool(key string, df bool) bool {
+	val, ok := os.LookupEnv(key)
+	if !ok {
+		log.Printf("Using default value for %s (%t)", key, df)
+		return df
+	}
+
+	b, err := strconv.ParseBool(val)
+	if err != nil {
+		log.Printf("Invalid boolean for %s (%s), using default value (%t)", key, val, df)
+		return df
+	}
+	return b
+}
+
+// GetEnvInt reads an integer and falls back to df when missing or invalid
+func GetEnvInt(key string, df int) int {
+	val, ok := os.LookupEnv(key)
+	if !ok {
+		log.Printf("Using default value for %s (%d)", key, df)
+		return df
+	}
+
+	i, err := strconv.Atoi(val)
+	if err != nil {
+		log.Printf("Invalid integer for %s (%s), using default value (%d)", key, val, df)
+		return df
+	}
+	return i
+}
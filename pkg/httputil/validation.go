@@ -0,0 +1,134 @@
+package httputil
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/ciameksw/mood-api/pkg/logger"
+	"github.com/go-playground/validator/v10"
+)
+
+// FieldError describes why a single request field was rejected.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// validationResponse is the response structure for rejected request payloads.
+type validationResponse struct {
+	Error  string       `json:"error"`
+	Fields []FieldError `json:"fields"`
+}
+
+// NewValidator creates a validator that reports fields by their JSON names.
+func NewValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return f.Name
+		}
+		return name
+	})
+	return v
+}
+
+// WriteValidationErrors logs the rejected fields and sends a validation error response.
+func WriteValidationErrors(logger logger.Logger, w http.ResponseWriter, fields []FieldError) {
+	logger.Error.Printf("Validation failed: %v", fields)
+	resp := validationResponse{
+		Error:  "Validation failed",
+		Fields: fields,
+	}
+	writeJSON(w, resp, http.StatusBadRequest)
+}
+
+// HandleValidationError sends the field errors of a failed validator check, other errors are sent as they are.
+func HandleValidationError(logger logger.Logger, w http.ResponseWriter, err error) {
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		HandleError(logger, w, err.Error(), err, http.StatusBadRequest)
+		return
+	}
+
+	fields := make([]FieldError, 0, len(validationErrs))
+	for _, fe := range validationErrs {
+		fields = append(fields, FieldError{
+			Field:   fieldPath(fe),
+			Message: fieldMessage(fe),
+		})
+	}
+	WriteValidationErrors(logger, w, fields)
+}
+
+// Helper function to get the field path without the name of the validated struct, e.g. "entries[0].date"
+func fieldPath(fe validator.FieldError) string {
+	ns := fe.Namespace()
+	if strings.HasPrefix(ns, "[") {
+		return ns
+	}
+	if _, path, ok := strings.Cut(ns, "."); ok {
+		return path
+	}
+	return fe.Field()
+}
+
+// Helper function to describe a failed validation tag in words
+func fieldMessage(fe validator.FieldError) string {
+	unit := ""
+	switch fe.Kind() {
+	case reflect.String:
+		unit = " characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		unit = " items"
+	}
+
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "required_without":
+		return "is required when " + lowerFirst(fe.Param()) + " is not provided"
+	case "excluded_with":
+		return "cannot be combined with " + lowerFirst(fe.Param())
+	case "min":
+		return "must be at least " + fe.Param() + unit
+	case "max":
+		return "must be at most " + fe.Param() + unit
+	case "len":
+		return "must be exactly " + fe.Param() + unit
+	case "email":
+		return "must be a valid email address"
+	case "numeric":
+		return "must contain only digits"
+	case "oneof":
+		return "must be one of: " + strings.Join(strings.Fields(fe.Param()), ", ")
+	case "datetime":
+		switch fe.Param() {
+		case "2006-01-02":
+			return "must be a date in YYYY-MM-DD format"
+		case "15:04":
+			return "must be a time in HH:MM format"
+		case "2006-01-02T15:04:05Z07:00":
+			return "must be an RFC 3339 timestamp such as 2026-01-03T08:15:00+01:00"
+		}
+		return "must be a date in " + fe.Param() + " format"
+	case "timezone":
+		return "must be an IANA time zone such as Europe/Warsaw"
+	case "bcp47_language_tag":
+		return "must be a language tag such as en or pt-BR"
+	default:
+		return "failed the " + fe.Tag() + " check"
+	}
+}
+
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToLower(s[:1]) + s[1:]
+}
@@ -0,0 +1,73 @@
+package localtime
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	// Service images have no zoneinfo, so the time zone database is compiled in
+	_ "time/tzdata"
+)
+
+// Headers the gateway sets from the user's preferences on requests to the services
+const (
+	TimezoneHeader  = "X-User-Timezone"
+	WeekStartHeader = "X-User-Week-Start"
+)
+
+const (
+	DefaultTimezone  = "UTC"
+	DefaultWeekStart = "monday"
+)
+
+var weekdays = map[string]time.Weekday{
+	"monday":   time.Monday,
+	"saturday": time.Saturday,
+	"sunday":   time.Sunday,
+}
+
+// Headers returns the preference headers of r to pass on to another service
+func Headers(r *http.Request) map[string]string {
+	headers := map[string]string{}
+	if tz := r.Header.Get(TimezoneHeader); tz != "" {
+		headers[TimezoneHeader] = tz
+	}
+	if ws := r.Header.Get(WeekStartHeader); ws != "" {
+		headers[WeekStartHeader] = ws
+	}
+	return headers
+}
+
+// Location returns the user's time zone, UTC when it is missing or unknown
+func Location(r *http.Request) *time.Location {
+	// "Local" would be the server's zone, which is exactly what the header replaces
+	tz := r.Header.Get(TimezoneHeader)
+	if tz == "" || strings.EqualFold(tz, "local") {
+		return time.UTC
+	}
+
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// WeekStart returns the first day of the user's week, Monday when it is missing or unknown
+func WeekStart(r *http.Request) time.Weekday {
+	if day, ok := ParseWeekday(r.Header.Get(WeekStartHeader)); ok {
+		return day
+	}
+	return time.Monday
+}
+
+// ParseWeekday reads a first day of the week, one of monday, saturday or sunday
+func ParseWeekday(s string) (time.Weekday, bool) {
+	day, ok := weekdays[strings.ToLower(s)]
+	return day, ok
+}
+
+// Today returns the current date in the user's time zone
+func Today(r *http.Request) string {
+	return time.Now().In(Location(r)).Format("2006-01-02")
+}
@@ -0,0 +1,142 @@
+package queryutil
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cursor marks where the next page of a keyset-paginated list starts, clients only see it encoded
+type Cursor struct {
+	Sort      string `json:"s"`
+	Key       string `json:"k"` // Sort key of the last item of the previous page
+	ID        int    `json:"i"` // Id of that item, it orders items with the same key
+	StartDate string `json:"f,omitempty"`
+	EndDate   string `json:"t,omitempty"`
+}
+
+type PageParams struct {
+	Limit  int
+	Sort   string
+	Cursor *Cursor // nil for the first page
+}
+
+// Page is one page of a list, NextCursor is nil on the last page
+type Page[T any] struct {
+	Items      []T     `json:"items"`
+	NextCursor *string `json:"nextCursor"`
+}
+
+// ParsePageParams reads the limit, sort and cursor parameters, sorts lists the accepted sort orders with the default first.
+// A cursor keeps the sort order of the page it was returned with.
+func ParsePageParams(r *http.Request, sorts []string, defaultLimit, maxLimit int) (*PageParams, error) {
+	q := r.URL.Query()
+	page := &PageParams{
+		Limit: defaultLimit,
+		Sort:  sorts[0],
+	}
+
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxLimit {
+			return nil, errors.New("limit must be between 1 and " + strconv.Itoa(maxLimit))
+		}
+		page.Limit = limit
+	}
+
+	sort := q.Get("sort")
+	if sort != "" && !slices.Contains(sorts, sort) {
+		return nil, errors.New("sort must be one of: " + strings.Join(sorts, ", "))
+	}
+
+	if v := q.Get("cursor"); v != "" {
+		cursor, err := DecodeCursor(v)
+		if err != nil || !slices.Contains(sorts, cursor.Sort) {
+			return nil, errors.New("invalid cursor")
+		}
+		if sort != "" && sort != cursor.Sort {
+			return nil, errors.New("sort cannot be changed when a cursor is given")
+		}
+		page.Cursor = cursor
+		page.Sort = cursor.Sort
+		return page, nil
+	}
+
+	if sort != "" {
+		page.Sort = sort
+	}
+	return page, nil
+}
+
+// ParsePagedTimeframeWithUserIDParams reads the user, date range and page, the date range is taken from the cursor when one is given
+func ParsePagedTimeframeWithUserIDParams(r *http.Request, sorts []string, defaultLimit, maxLimit int) (*GetParams, *PageParams, error) {
+	page, err := ParsePageParams(r, sorts, defaultLimit, maxLimit)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if page.Cursor == nil {
+		input, err := ParseTimeframeWithUserIDParams(r)
+		if err != nil {
+			return nil, nil, err
+		}
+		return input, page, nil
+	}
+
+	q := r.URL.Query()
+	if q.Get("from") != "" || q.Get("to") != "" || q.Get("period") != "" {
+		return nil, nil, errors.New("from, to and period cannot be combined with a cursor")
+	}
+	if _, err := time.Parse(dateFormat, page.Cursor.StartDate); err != nil {
+		return nil, nil, errors.New("invalid cursor")
+	}
+	if _, err := time.Parse(dateFormat, page.Cursor.EndDate); err != nil {
+		return nil, nil, errors.New("invalid cursor")
+	}
+
+	userID, err := strconv.Atoi(q.Get("userId"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &GetParams{
+		UserID:    userID,
+		StartDate: page.Cursor.StartDate,
+		EndDate:   page.Cursor.EndDate,
+	}, page, nil
+}
+
+// NewPage builds a page from up to limit+1 items, the extra item only tells that there is a next page.
+// next returns the cursor after the given item.
+func NewPage[T any](items []T, limit int, next func(last T) Cursor) Page[T] {
+	if len(items) <= limit {
+		return Page[T]{Items: items}
+	}
+
+	items = items[:limit]
+	cursor := EncodeCursor(next(items[limit-1]))
+	return Page[T]{Items: items, NextCursor: &cursor}
+}
+
+func EncodeCursor(c Cursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func DecodeCursor(s string) (*Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	var c Cursor
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
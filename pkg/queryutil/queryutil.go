@@ -5,18 +5,30 @@ import (
 	"net/http"
 	"strconv"
 	"time"
+
+	"github.com/ciameksw/mood-api/pkg/localtime"
 )
 
+const dateFormat = "2006-01-02"
+
+// ParseTimeframeParams reads the from and to dates, or a named period resolved in the user's time zone and week
 func ParseTimeframeParams(r *http.Request) (string, string, error) {
 	from := r.URL.Query().Get("from")
 	to := r.URL.Query().Get("to")
 
+	if period := r.URL.Query().Get("period"); period != "" {
+		if from != "" || to != "" {
+			return "", "", errors.New("use either period or from and to")
+		}
+		today := time.Now().In(localtime.Location(r))
+		return ResolvePeriod(period, today, localtime.WeekStart(r))
+	}
+
 	if from == "" || to == "" {
 		return "", "", errors.New("from and to parameters are required")
 	}
 
 	// Validate date format YYYY-MM-DD
-	const dateFormat = "2006-01-02"
 	fromDate, err := time.Parse(dateFormat, from)
 	if err != nil {
 		return "", "", errors.New("from date must be in YYYY-MM-DD format")
@@ -34,6 +46,38 @@ func ParseTimeframeParams(r *http.Request) (string, string, error) {
 	return from, to, nil
 }
 
+// ResolvePeriod turns a named period into from and to dates relative to today, weeks begin on weekStart
+func ResolvePeriod(period string, today time.Time, weekStart time.Weekday) (string, string, error) {
+	today = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC)
+	weekBegin := today.AddDate(0, 0, -((int(today.Weekday()) - int(weekStart) + 7) % 7))
+	monthBegin := today.AddDate(0, 0, 1-today.Day())
+
+	var from, to time.Time
+	switch period {
+	case "today":
+		from, to = today, today
+	case "yesterday":
+		from = today.AddDate(0, 0, -1)
+		to = from
+	case "this_week":
+		from, to = weekBegin, today
+	case "last_week":
+		from, to = weekBegin.AddDate(0, 0, -7), weekBegin.AddDate(0, 0, -1)
+	case "this_month":
+		from, to = monthBegin, today
+	case "last_month":
+		from, to = monthBegin.AddDate(0, -1, 0), monthBegin.AddDate(0, 0, -1)
+	case "last_7_days":
+		from, to = today.AddDate(0, 0, -6), today
+	case "last_30_days":
+		from, to = today.AddDate(0, 0, -29), today
+	default:
+		return "", "", errors.New("period must be one of: today, yesterday, this_week, last_week, this_month, last_month, last_7_days, last_30_days")
+	}
+
+	return from.Format(dateFormat), to.Format(dateFormat), nil
+}
+
 type GetParams struct {
 	UserID    int
 	StartDate string
@@ -0,0 +1,15 @@
+\connect mood_api_db
+
+CREATE TABLE IF NOT EXISTS public.refresh_tokens (
+	id SERIAL PRIMARY KEY,
+	user_id INT NOT NULL REFERENCES public.users(id) ON DELETE CASCADE,
+	family_id VARCHAR(64) NOT NULL, -- All tokens rotated from the same login share a family
+	token_hash VARCHAR(64) UNIQUE NOT NULL,
+	expires_at TIMESTAMP NOT NULL,
+	revoked_at TIMESTAMP,
+	replaced_by INT REFERENCES public.refresh_tokens(id) ON DELETE SET NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS refresh_tokens_family_id_idx ON public.refresh_tokens (family_id);
+CREATE INDEX IF NOT EXISTS refresh_tokens_user_id_idx ON public.refresh_tokens (user_id);
@@ -0,0 +1,15 @@
+\connect mood_api_db
+
+-- Issued access tokens, consulted on every authorization so they can be revoked before they expire.
+-- Rows are kept after the user is deleted so the revocation stays effective until expiry.
+CREATE TABLE IF NOT EXISTS public.access_tokens (
+	jti VARCHAR(64) PRIMARY KEY,
+	user_id INT NOT NULL,
+	family_id VARCHAR(64) NOT NULL, -- Refresh token family of the login that issued the token
+	issued_at TIMESTAMP NOT NULL,
+	expires_at TIMESTAMP NOT NULL,
+	revoked_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS access_tokens_user_id_idx ON public.access_tokens (user_id);
+CREATE INDEX IF NOT EXISTS access_tokens_family_id_idx ON public.access_tokens (family_id);
@@ -0,0 +1,4 @@
+\connect mood_api_db
+
+-- Supports the incremental revocation feed polled by the gateway
+CREATE INDEX IF NOT EXISTS access_tokens_revoked_at_idx ON public.access_tokens (revoked_at) WHERE revoked_at IS NOT NULL;
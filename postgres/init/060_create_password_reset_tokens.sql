@@ -0,0 +1,12 @@
+\connect mood_api_db
+
+CREATE TABLE IF NOT EXISTS public.password_reset_tokens (
+	id SERIAL PRIMARY KEY,
+	user_id INT NOT NULL REFERENCES public.users(id) ON DELETE CASCADE,
+	token_hash VARCHAR(64) UNIQUE NOT NULL,
+	expires_at TIMESTAMP NOT NULL,
+	used_at TIMESTAMP,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS password_reset_tokens_user_id_idx ON public.password_reset_tokens (user_id);
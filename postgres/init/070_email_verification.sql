@@ -0,0 +1,25 @@
+\connect mood_api_db
+
+DO $$
+BEGIN
+	IF NOT EXISTS (
+		SELECT 1 FROM information_schema.columns
+		WHERE table_schema = 'public' AND table_name = 'users' AND column_name = 'verified_at'
+	) THEN
+		ALTER TABLE public.users ADD COLUMN verified_at TIMESTAMP;
+		-- Accounts created before verification existed are treated as verified
+		UPDATE public.users SET verified_at = created_at;
+	END IF;
+END $$;
+
+CREATE TABLE IF NOT EXISTS public.email_verification_tokens (
+	id SERIAL PRIMARY KEY,
+	user_id INT NOT NULL REFERENCES public.users(id) ON DELETE CASCADE,
+	email VARCHAR(100) NOT NULL, -- Address being verified, a later email change makes the token useless
+	token_hash VARCHAR(64) UNIQUE NOT NULL,
+	expires_at TIMESTAMP NOT NULL,
+	used_at TIMESTAMP,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS email_verification_tokens_user_id_idx ON public.email_verification_tokens (user_id);
@@ -0,0 +1,18 @@
+\connect mood_api_db
+
+CREATE TABLE IF NOT EXISTS public.user_two_factor (
+	user_id INT PRIMARY KEY REFERENCES public.users(id) ON DELETE CASCADE,
+	secret_encrypted TEXT NOT NULL, -- AES-GCM encrypted TOTP secret
+	confirmed_at TIMESTAMP, -- NULL while enrollment is pending
+	last_used_step BIGINT NOT NULL DEFAULT 0, -- Last accepted TOTP time step, prevents code replay
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS public.recovery_codes (
+	id SERIAL PRIMARY KEY,
+	user_id INT NOT NULL REFERENCES public.users(id) ON DELETE CASCADE,
+	code_hash VARCHAR(64) NOT NULL,
+	used_at TIMESTAMP,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (user_id, code_hash)
+);
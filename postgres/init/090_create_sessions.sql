@@ -0,0 +1,15 @@
+\connect mood_api_db
+
+-- One row per login, the id is the refresh token family id carried in tokens as the sid claim
+CREATE TABLE IF NOT EXISTS public.sessions (
+	id VARCHAR(32) PRIMARY KEY,
+	user_id INT NOT NULL REFERENCES public.users(id) ON DELETE CASCADE,
+	user_agent TEXT NOT NULL DEFAULT '',
+	ip_address VARCHAR(45) NOT NULL DEFAULT '',
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	last_seen_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	expires_at TIMESTAMP NOT NULL,
+	revoked_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS sessions_user_id_idx ON public.sessions (user_id);
@@ -0,0 +1,8 @@
+\connect mood_api_db
+
+CREATE TABLE IF NOT EXISTS public.user_roles (
+	user_id INT NOT NULL REFERENCES public.users(id) ON DELETE CASCADE,
+	role VARCHAR(30) NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (user_id, role)
+);
@@ -0,0 +1,22 @@
+\connect mood_api_db
+
+CREATE TABLE IF NOT EXISTS public.user_identities (
+	id SERIAL PRIMARY KEY,
+	user_id INT NOT NULL REFERENCES public.users(id) ON DELETE CASCADE,
+	provider VARCHAR(50) NOT NULL,
+	subject VARCHAR(255) NOT NULL,
+	email VARCHAR(100),
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	last_login_at TIMESTAMP,
+	UNIQUE (provider, subject),
+	UNIQUE (user_id, provider)
+);
+
+CREATE TABLE IF NOT EXISTS public.oidc_login_states (
+	state_hash VARCHAR(64) PRIMARY KEY,
+	provider VARCHAR(50) NOT NULL,
+	nonce VARCHAR(64) NOT NULL,
+	code_verifier VARCHAR(128) NOT NULL,
+	expires_at TIMESTAMP NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,20 @@
+\connect mood_api_db
+
+-- A deleted account keeps its row until the grace period ends and its data is purged
+ALTER TABLE public.users ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+ALTER TABLE public.users ADD COLUMN IF NOT EXISTS purge_after TIMESTAMP;
+ALTER TABLE public.users ADD COLUMN IF NOT EXISTS restore_token_hash VARCHAR(64) UNIQUE;
+
+CREATE INDEX IF NOT EXISTS users_purge_after_idx ON public.users (purge_after) WHERE deleted_at IS NOT NULL;
+
+-- No foreign key to users, the audit trail outlives the purged account
+CREATE TABLE IF NOT EXISTS public.account_deletion_audit (
+	id SERIAL PRIMARY KEY,
+	user_id INT NOT NULL,
+	action VARCHAR(30) NOT NULL, -- requested, restored, purge_failed or purged
+	actor_id INT, -- User who requested or restored, NULL for the purge job
+	detail TEXT,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS account_deletion_audit_user_id_idx ON public.account_deletion_audit (user_id);
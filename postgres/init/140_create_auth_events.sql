@@ -0,0 +1,27 @@
+\connect mood_api_db
+
+-- No foreign key to users, failed logins may name no account and events outlive deleted accounts until retention removes them
+CREATE TABLE IF NOT EXISTS public.auth_events (
+	id BIGSERIAL PRIMARY KEY,
+	user_id INT,
+	event_type VARCHAR(50) NOT NULL,
+	outcome VARCHAR(20) NOT NULL, -- success or failure
+	ip_address VARCHAR(45),
+	user_agent TEXT,
+	detail TEXT,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS auth_events_user_id_created_at_idx ON public.auth_events (user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS auth_events_created_at_idx ON public.auth_events (created_at);
+
+-- Events are append-only, rows may only be removed by the retention job
+CREATE OR REPLACE FUNCTION public.reject_auth_event_update() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION 'auth_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS auth_events_append_only ON public.auth_events;
+CREATE TRIGGER auth_events_append_only BEFORE UPDATE ON public.auth_events
+	FOR EACH ROW EXECUTE FUNCTION public.reject_auth_event_update();
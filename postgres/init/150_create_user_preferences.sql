@@ -0,0 +1,12 @@
+\connect mood_api_db
+
+-- Users without a row use the defaults below
+CREATE TABLE IF NOT EXISTS public.user_preferences (
+	user_id INT PRIMARY KEY REFERENCES public.users(id) ON DELETE CASCADE,
+	timezone VARCHAR(64) NOT NULL DEFAULT 'UTC', -- IANA time zone name
+	locale VARCHAR(35) NOT NULL DEFAULT 'en', -- BCP 47 language tag
+	week_start VARCHAR(10) NOT NULL DEFAULT 'monday' CHECK (week_start IN ('monday', 'saturday', 'sunday')),
+	reminder_enabled BOOLEAN NOT NULL DEFAULT FALSE,
+	reminder_time VARCHAR(5) NOT NULL DEFAULT '20:00', -- HH:MM in the user's time zone
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
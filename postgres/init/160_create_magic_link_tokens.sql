@@ -0,0 +1,14 @@
+\connect mood_api_db
+
+CREATE TABLE IF NOT EXISTS public.magic_link_tokens (
+	id SERIAL PRIMARY KEY,
+	user_id INT NOT NULL REFERENCES public.users(id) ON DELETE CASCADE,
+	email VARCHAR(100) NOT NULL, -- The link only logs in while the account still has this address
+	token_hash VARCHAR(64) UNIQUE NOT NULL,
+	device_hash VARCHAR(64) NOT NULL, -- Hash of the device token handed to the client that requested the link
+	expires_at TIMESTAMP NOT NULL,
+	used_at TIMESTAMP,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS magic_link_tokens_user_id_idx ON public.magic_link_tokens (user_id);
@@ -0,0 +1,21 @@
+\connect mood_api_db
+
+-- A mood entry is a check-in at a moment in time, mood_date is its calendar date in the user's time zone
+ALTER TABLE public.mood ADD COLUMN IF NOT EXISTS logged_at TIMESTAMPTZ;
+ALTER TABLE public.mood ADD COLUMN IF NOT EXISTS timezone VARCHAR(64) NOT NULL DEFAULT 'UTC'; -- IANA time zone name at check-in
+
+-- Entries from before check-ins only had a date, they are placed at noon of that date in the user's time zone
+UPDATE public.mood m
+SET logged_at = (m.mood_date + TIME '12:00') AT TIME ZONE COALESCE(up.timezone, 'UTC'),
+	timezone = COALESCE(up.timezone, 'UTC')
+FROM public.users u
+LEFT JOIN public.user_preferences up ON up.user_id = u.id
+WHERE u.id = m.user_id AND m.logged_at IS NULL;
+
+ALTER TABLE public.mood ALTER COLUMN logged_at SET DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE public.mood ALTER COLUMN logged_at SET NOT NULL;
+
+-- Any number of check-ins per day
+ALTER TABLE public.mood DROP CONSTRAINT IF EXISTS mood_user_id_mood_date_key;
+
+CREATE INDEX IF NOT EXISTS mood_user_id_mood_date_idx ON public.mood (user_id, mood_date, logged_at);
@@ -0,0 +1,24 @@
+\connect mood_api_db
+
+-- How strongly the mood was felt, NULL when the user did not say
+ALTER TABLE public.mood ADD COLUMN IF NOT EXISTS intensity SMALLINT CHECK (intensity BETWEEN 1 AND 10);
+
+-- Position of the mood type on the circumplex model, from -1 (unpleasant, deactivated) to 1 (pleasant, activated)
+ALTER TABLE public.mood_type ADD COLUMN IF NOT EXISTS valence NUMERIC(3, 2) CHECK (valence BETWEEN -1 AND 1);
+ALTER TABLE public.mood_type ADD COLUMN IF NOT EXISTS arousal NUMERIC(3, 2) CHECK (arousal BETWEEN -1 AND 1);
+
+UPDATE public.mood_type t
+SET valence = v.valence, arousal = v.arousal
+FROM (VALUES
+	('Happy', 0.80, 0.50),
+	('Sad', -0.70, -0.40),
+	('Anxious', -0.60, 0.70),
+	('Calm', 0.60, -0.60),
+	('Energetic', 0.60, 0.80),
+	('Tired', -0.30, -0.80),
+	('Angry', -0.70, 0.80),
+	('Grateful', 0.80, 0.10),
+	('Stressed', -0.60, 0.60),
+	('Neutral', 0.00, 0.00)
+) AS v (name, valence, arousal)
+WHERE t.name = v.name AND t.valence IS NULL AND t.arousal IS NULL;
@@ -0,0 +1,18 @@
+\connect mood_api_db
+
+-- Tags are defined per user, names are stored lowercase
+CREATE TABLE IF NOT EXISTS public.mood_tag (
+	id SERIAL PRIMARY KEY,
+	user_id INT NOT NULL REFERENCES public.users(id),
+	name VARCHAR(30) NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (user_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS public.mood_entry_tag (
+	mood_id INT NOT NULL REFERENCES public.mood(id) ON DELETE CASCADE,
+	tag_id INT NOT NULL REFERENCES public.mood_tag(id) ON DELETE CASCADE,
+	PRIMARY KEY (mood_id, tag_id)
+);
+
+CREATE INDEX IF NOT EXISTS mood_entry_tag_tag_id_idx ON public.mood_entry_tag (tag_id);
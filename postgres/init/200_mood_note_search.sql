@@ -0,0 +1,7 @@
+\connect mood_api_db
+
+-- Full-text search over notes, the text search configuration has to match the one the mood service queries with
+ALTER TABLE public.mood ADD COLUMN IF NOT EXISTS note_tsv TSVECTOR
+	GENERATED ALWAYS AS (to_tsvector('english', COALESCE(note, ''))) STORED;
+
+CREATE INDEX IF NOT EXISTS mood_note_tsv_idx ON public.mood USING GIN (note_tsv);
@@ -21,9 +21,12 @@ func NewRedisCache(addr string) *RedisCache {
 	}
 }
 
-// GetTodayQuote retrieves today's quote from cache
-func (rc *RedisCache) GetTodayQuote(ctx context.Context) (interface{}, error) {
-	key := getTodayKey()
+// Time zones are up to 26 hours apart, so a date stays "today" somewhere for up to 50 hours
+const todayQuoteTTL = 50 * time.Hour
+
+// GetTodayQuote retrieves today's quote in the given time zone from cache
+func (rc *RedisCache) GetTodayQuote(ctx context.Context, loc *time.Location) (interface{}, error) {
+	key := getTodayKey(loc)
 	val, err := rc.client.Get(ctx, key).Result()
 	if err == redis.Nil {
 		return nil, nil // Cache miss
@@ -40,20 +43,20 @@ func (rc *RedisCache) GetTodayQuote(ctx context.Context) (interface{}, error) {
 	return data, nil
 }
 
-// SetTodayQuote caches today's quote with 24-hour TTL
-func (rc *RedisCache) SetTodayQuote(ctx context.Context, quote interface{}) error {
-	key := getTodayKey()
+// SetTodayQuote caches today's quote in the given time zone until the date has passed everywhere
+func (rc *RedisCache) SetTodayQuote(ctx context.Context, loc *time.Location, quote interface{}) error {
+	key := getTodayKey(loc)
 	jsonData, err := json.Marshal(quote)
 	if err != nil {
 		return err
 	}
 
-	return rc.client.Set(ctx, key, jsonData, 24*time.Hour).Err()
+	return rc.client.Set(ctx, key, jsonData, todayQuoteTTL).Err()
 }
 
-// getTodayKey returns a cache key for today's date
-func getTodayKey() string {
-	return "quote:today:" + time.Now().Format("2006-01-02")
+// getTodayKey returns a cache key for today's date in the given time zone
+func getTodayKey(loc *time.Location) string {
+	return "quote:today:" + time.Now().In(loc).Format("2006-01-02")
 }
 
 // Close closes the Redis connection
@@ -5,14 +5,16 @@ import (
 	"net/http"
 
 	"github.com/ciameksw/mood-api/pkg/httputil"
+	"github.com/ciameksw/mood-api/pkg/localtime"
 )
 
 func (s *Server) handleGetTodayQuote(w http.ResponseWriter, r *http.Request) {
 	s.Logger.Info.Println("Getting today's quote")
 
 	ctx := context.Background()
+	loc := localtime.Location(r)
 
-	cachedQuote, err := s.RedisCache.GetTodayQuote(ctx)
+	cachedQuote, err := s.RedisCache.GetTodayQuote(ctx, loc)
 	if err == nil && cachedQuote != nil {
 		s.Logger.Info.Println("Quote found in cache")
 		httputil.WriteData(*s.Logger, w, cachedQuote, http.StatusOK)
@@ -25,7 +27,7 @@ func (s *Server) handleGetTodayQuote(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := s.RedisCache.SetTodayQuote(ctx, resp); err != nil {
+	if err := s.RedisCache.SetTodayQuote(ctx, loc, resp); err != nil {
 		s.Logger.Error.Printf("Failed to cache quote: %v", err)
 	}
 